@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{input: "y\n", expected: true},
+		{input: "YES\n", expected: true},
+		{input: " yes ", expected: true},
+		{input: "n\n", expected: false},
+		{input: "\n", expected: false},
+		{input: "", expected: false},
+	}
+
+	for _, tc := range tests {
+		out := &bytes.Buffer{}
+		ok, err := Confirm(strings.NewReader(tc.input), out, "Continue?")
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, ok, tc.input)
+		assert.Equal(t, "Continue? [y/N]: ", out.String())
+	}
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -25,6 +26,20 @@ func (b *GroupedError) Error() string {
 	return b.msg
 }
 
+// ExitCodeError is an error that makes the command exit with a specific exit code
+type ExitCodeError struct {
+	msg  string
+	Code int
+}
+
+func NewExitCodeError(msg string, code int) *ExitCodeError {
+	return &ExitCodeError{msg: msg, Code: code}
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.msg
+}
+
 type DeprecationOptions struct {
 	Site      string
 	Component string
@@ -54,6 +69,13 @@ func PrintExitError(summary string, detail ...string) {
 }
 
 func HandleErr(err error) {
+	// The error is the outcome of the command rather than a failure, for example detected drift
+	var exitErr *ExitCodeError
+	if errors.As(err, &exitErr) {
+		log.Error().Msg(exitErr.Error())
+		os.Exit(exitErr.Code)
+	}
+
 	log.Error().Msgf("Error: %v\n", err)
 	if openApiErr, ok := err.(*mccsdk.GenericOpenAPIError); ok {
 		remoteErr := openApiErr.Model()
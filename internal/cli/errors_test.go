@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleErrExitCode runs HandleErr in a subprocess, as it exits the process
+func TestHandleErrExitCode(t *testing.T) {
+	if os.Getenv("MC_TEST_HANDLE_ERR") == "1" {
+		HandleErr(fmt.Errorf("drift: %w", NewExitCodeError("drift detected", 2)))
+		return
+	}
+
+	tests := map[string]int{
+		"TestHandleErrExitCode":   2,
+		"TestHandleErrOtherError": 1,
+	}
+	for name, expected := range tests {
+		cmd := exec.Command(os.Args[0], "-test.run=^"+name+"$")
+		cmd.Env = append(os.Environ(), "MC_TEST_HANDLE_ERR=1")
+		err := cmd.Run()
+
+		var exitErr *exec.ExitError
+		require.ErrorAs(t, err, &exitErr, name)
+		assert.Equal(t, expected, exitErr.ExitCode(), name)
+	}
+}
+
+func TestHandleErrOtherError(t *testing.T) {
+	if os.Getenv("MC_TEST_HANDLE_ERR") == "1" {
+		HandleErr(errors.New("failed"))
+	}
+}
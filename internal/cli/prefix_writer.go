@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// PrefixWriter prefixes every line written to it. Only complete lines are written to the underlying writer, while
+// holding the given lock, so the output of writers sharing the lock is never mixed within a line.
+type PrefixWriter struct {
+	w      io.Writer
+	prefix []byte
+	lock   *sync.Mutex
+	buf    []byte
+}
+
+func NewPrefixWriter(w io.Writer, prefix string, lock *sync.Mutex) *PrefixWriter {
+	return &PrefixWriter{w: w, prefix: []byte(prefix), lock: lock}
+}
+
+func (w *PrefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	i := bytes.LastIndexByte(w.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+
+	if err := w.writeLines(w.buf[:i+1]); err != nil {
+		return 0, err
+	}
+	w.buf = w.buf[i+1:]
+	return len(p), nil
+}
+
+// Flush writes the remaining incomplete line, if any
+func (w *PrefixWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	err := w.writeLines(append(w.buf, '\n'))
+	w.buf = nil
+	return err
+}
+
+func (w *PrefixWriter) writeLines(lines []byte) error {
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		out.Write(w.prefix)
+		out.Write(line)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	_, err := w.w.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	lock := &sync.Mutex{}
+	w := NewPrefixWriter(&buf, "[site/component] ", lock)
+
+	_, err := w.Write([]byte("first line\nsecond "))
+	require.NoError(t, err)
+	assert.Equal(t, "[site/component] first line\n", buf.String())
+
+	_, err = w.Write([]byte("line\nthird\n\nincomplete"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "[site/component] first line\n"+
+		"[site/component] second line\n"+
+		"[site/component] third\n"+
+		"[site/component] \n"+
+		"[site/component] incomplete\n", buf.String())
+
+	// Nothing is left to flush
+	require.NoError(t, w.Flush())
+	assert.Equal(t, 5, bytes.Count(buf.Bytes(), []byte("\n")))
+}
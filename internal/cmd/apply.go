@@ -1,9 +1,11 @@
 package cmd
 
 import (
-	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"fmt"
+	"os"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
-	"github.com/mach-composer/mach-composer-cli/internal/hash"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
@@ -16,8 +18,17 @@ var applyFlags struct {
 	autoApprove           bool
 	destroy               bool
 	components            []string
+	withDependencies      bool
+	withDependents        bool
 	numWorkers            int
 	ignoreChangeDetection bool
+	keepGoing             bool
+	dryRun                bool
+	replanStale           bool
+	planFirst             bool
+	autoApproveNoDestroy  bool
+	allowDestroy          bool
+	allowLayoutChange     bool
 }
 
 var applyCmd = &cobra.Command{
@@ -34,23 +45,32 @@ var applyCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(applyCmd)
+	registerRunnerFlags(applyCmd)
 	applyCmd.Flags().BoolVarP(&applyFlags.forceInit, "force-init", "", false, "Force terraform initialization. By default mach-composer will reuse existing terraform resources")
 	applyCmd.Flags().BoolVarP(&applyFlags.autoApprove, "auto-approve", "", false, "Suppress a terraform init for improved speed (not recommended for production usage)")
-	applyCmd.Flags().BoolVarP(&applyFlags.destroy, "destroy", "", false, "Destroy option is a convenient way to destroy all remote objects managed by this mach config")
-	applyCmd.Flags().StringArrayVarP(&applyFlags.components, "component", "c", nil, "")
+	applyCmd.Flags().BoolVarP(&applyFlags.destroy, "destroy", "", false, "Destroy option is a convenient way to destroy all remote objects managed by this mach config. Components are destroyed in reverse dependency order, after confirmation")
+	applyCmd.Flags().StringArrayVarP(&applyFlags.components, "component", "c", nil, "Component to run. Can be repeated to select multiple components. If not set run all components.")
+	applyCmd.Flags().BoolVarP(&applyFlags.withDependencies, "with-dependencies", "", false, "Also run the components the selected components depend on")
+	applyCmd.Flags().BoolVarP(&applyFlags.withDependents, "with-dependents", "", false, "Also run the components that depend on the selected components")
 	applyCmd.Flags().BoolVarP(&applyFlags.ignoreChangeDetection, "ignore-change-detection", "", false, "Ignore change detection to run even if the components are considered up to date")
+	applyCmd.Flags().BoolVarP(&applyFlags.keepGoing, "keep-going", "", false, "Continue running the components that do not depend on a failed component, and report the outcome of every component at the end")
+	applyCmd.Flags().BoolVarP(&applyFlags.replanStale, "replan-stale", "", false, "Discard saved plans that no longer match the configuration and plan again, instead of failing")
+
+	applyCmd.Flags().BoolVarP(&applyFlags.planFirst, "plan-first", "", false, "Plan all components first, show a summary of all changes and ask for a single confirmation before applying the saved plans")
+	applyCmd.Flags().BoolVarP(&applyFlags.autoApproveNoDestroy, "auto-approve-no-destroy", "", false, "With --plan-first, skip the confirmation if no resources will be destroyed or replaced")
+	applyCmd.Flags().BoolVarP(&applyFlags.allowDestroy, "allow-destroy", "", false, "Apply saved plans that delete or replace protected resources")
+	applyCmd.Flags().BoolVarP(&applyFlags.allowLayoutChange, "allow-layout-change", "", false, "Apply even if the deployment type of components changed without migrating their state, which destroys and recreates their resources")
+	applyCmd.Flags().BoolVarP(&applyFlags.dryRun, "dry-run", "", false, "Show the batches, the components that are skipped and the terraform commands that would be run, without running them")
+
+	_ = applyCmd.RegisterFlagCompletionFunc("component", AutocompleteComponentName)
 }
 
 func applyFunc(cmd *cobra.Command, _ []string) error {
-	if len(applyFlags.components) > 0 {
-		log.Warn().Msgf("Components option not implemented")
-	}
-
 	cfg := loadConfig(cmd, true)
 	defer cfg.Close()
 	ctx := cmd.Context()
 
-	dg, err := graph.ToDeploymentGraph(cfg, commonFlags.outputPath)
+	dg, err := loadDeploymentGraph(cfg)
 	if err != nil {
 		return err
 	}
@@ -63,16 +83,144 @@ func applyFunc(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	r := runner.NewGraphRunner(
-		batcher.NaiveBatchFunc(),
-		hash.Factory(cfg),
-		commonFlags.workers,
-	)
+	targets, err := selectTargets(dg, applyFlags.components, &graph.SelectOptions{
+		WithDependencies: applyFlags.withDependencies,
+		WithDependents:   applyFlags.withDependents,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Terraform asks for confirmation unless it is given beforehand, or nothing is applied
+	input := !applyFlags.autoApprove && !applyFlags.planFirst && !applyFlags.destroy && !applyFlags.dryRun
+	r, err := newGraphRunner(ctx, cfg, input)
+	if err != nil {
+		return err
+	}
+
+	if applyFlags.planFirst {
+		return planFirstFunc(cmd, dg, r, targets)
+	}
+
+	autoApprove := applyFlags.autoApprove
+	if applyFlags.destroy && !applyFlags.dryRun {
+		ok, err := confirmDestroy(dg, r.DestroyOrder(dg, targets))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Info().Msg("Destroy cancelled")
+			return nil
+		}
+		// The destroy of all nodes is confirmed at once, so terraform should not ask again for every node. This would
+		// not even be possible when running in parallel, as stdin is not available then.
+		autoApprove = true
+	}
 
 	return r.TerraformApply(ctx, dg, &runner.ApplyOptions{
 		ForceInit:             applyFlags.forceInit,
 		Destroy:               applyFlags.destroy,
-		AutoApprove:           applyFlags.autoApprove,
+		AutoApprove:           autoApprove,
+		IgnoreChangeDetection: applyFlags.ignoreChangeDetection,
+		KeepGoing:             applyFlags.keepGoing,
+		DryRun:                applyFlags.dryRun,
+		ReplanStale:           applyFlags.replanStale,
+		AllowDestroy:          applyFlags.allowDestroy,
+		AllowLayoutChange:     applyFlags.allowLayoutChange,
+		Targets:               targets,
+	})
+}
+
+// planFirstFunc plans all components, asks for a single confirmation of all changes, and then applies the saved plans
+func planFirstFunc(cmd *cobra.Command, dg *graph.Graph, r *runner.GraphRunner, targets graph.Vertices) error {
+	if applyFlags.destroy || applyFlags.dryRun {
+		return fmt.Errorf("--plan-first cannot be combined with --destroy or --dry-run")
+	}
+	ctx := cmd.Context()
+
+	// Fail before planning, instead of after the changes have been confirmed
+	if err := r.CheckLayoutChanges(ctx, dg, applyFlags.allowLayoutChange); err != nil {
+		return err
+	}
+
+	err := r.TerraformPlan(ctx, dg, &runner.PlanOptions{
+		ForceInit:             applyFlags.forceInit,
+		Lock:                  true,
+		IgnoreChangeDetection: applyFlags.ignoreChangeDetection,
+		KeepGoing:             applyFlags.keepGoing,
+		Targets:               targets,
+	})
+	if err != nil {
+		return err
+	}
+
+	review, err := r.ReviewPlans(ctx, dg, &runner.ReviewOptions{
+		IgnoreChangeDetection: applyFlags.ignoreChangeDetection,
+		Targets:               targets,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(review.Plans) == 0 {
+		log.Info().Msg("No components to apply")
+		return nil
+	}
+	review.Write(os.Stdout)
+
+	ok, err := confirmPlans(review)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		log.Info().Msg("Apply cancelled")
+		return nil
+	}
+
+	// The saved plans do not require confirmation, and nodes without a plan are skipped
+	return r.TerraformApply(ctx, dg, &runner.ApplyOptions{
+		AutoApprove:           true,
 		IgnoreChangeDetection: applyFlags.ignoreChangeDetection,
+		KeepGoing:             applyFlags.keepGoing,
+		RequirePlan:           true,
+		AllowDestroy:          applyFlags.allowDestroy,
+		AllowLayoutChange:     applyFlags.allowLayoutChange,
+		Targets:               targets,
 	})
 }
+
+// confirmPlans asks for confirmation of the reviewed plans, unless there are no changes or auto-approve applies
+func confirmPlans(review *runner.PlanReview) (bool, error) {
+	switch {
+	case !review.HasChanges():
+		log.Info().Msg("No changes planned, applying to record the current configuration")
+		return true, nil
+	case applyFlags.autoApprove:
+		return true, nil
+	case applyFlags.autoApproveNoDestroy && !review.HasDestroys():
+		log.Info().Msg("No resources will be destroyed, applying without confirmation")
+		return true, nil
+	}
+
+	return cli.Confirm(os.Stdin, os.Stdout, "Do you want to apply these changes?")
+}
+
+// confirmDestroy lists the nodes that will be destroyed and asks for confirmation, unless auto-approve is set
+func confirmDestroy(dg *graph.Graph, nodes []graph.Node) (bool, error) {
+	if len(nodes) == 0 {
+		log.Info().Msg("No components to destroy")
+		return false, nil
+	}
+
+	fmt.Println("The following will be destroyed, in this order:")
+	for _, n := range nodes {
+		fmt.Printf(" - %s (%s)\n", dg.RelativePath(n), n.Type())
+	}
+	fmt.Println("")
+
+	if applyFlags.autoApprove {
+		return true, nil
+	}
+
+	return cli.Confirm(os.Stdin, os.Stdout, "Do you really want to destroy these components?")
+}
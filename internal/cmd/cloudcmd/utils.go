@@ -1,12 +1,11 @@
 package cloudcmd
 
 import (
-	"fmt"
 	"io"
-	"os"
 
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
 )
 
 func Must(err error) {
@@ -24,20 +23,5 @@ func MustGetString(cmd *cobra.Command, key string) string {
 }
 
 func writeTable(writer io.Writer, header []string, data [][]string) {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetAutoWrapText(false)
-	table.SetAutoFormatHeaders(true)
-	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
-	table.SetCenterSeparator("")
-	table.SetColumnSeparator("")
-	table.SetRowSeparator("-")
-	table.SetHeaderLine(true)
-	table.SetBorder(true)
-	table.SetTablePadding("\t") // pad with tabs
-	table.SetNoWhiteSpace(true)
-	table.SetHeader(header)
-	table.AppendBulk(data)
-	table.Render() // Send output
-	fmt.Println()
+	cli.WriteTable(writer, header, data)
 }
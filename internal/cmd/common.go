@@ -1,18 +1,26 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/mach-composer/mach-composer-cli/internal/cloud"
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
 	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
 )
 
 type CommonFlags struct {
@@ -22,6 +30,12 @@ type CommonFlags struct {
 	outputPath    string
 	varFile       string
 	workers       int
+	strategy      string
+	outputMode    string
+	terraformBin  string
+	timeout       time.Duration
+	nodeTimeout   time.Duration
+	gracePeriod   time.Duration
 }
 
 var commonFlags CommonFlags
@@ -29,7 +43,7 @@ var commonFlags CommonFlags
 func registerCommonFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&commonFlags.configFile, "file", "f", "main.yml", "YAML file to parse.")
 	cmd.Flags().StringVarP(&commonFlags.varFile, "var-file", "", "", "Use a variable file to parse the configuration with.")
-	cmd.Flags().StringVarP(&commonFlags.siteName, "site", "s", "", "Site to parse. If not set parse all sites.")
+	cmd.Flags().StringVarP(&commonFlags.siteName, "site", "s", "", "Site to parse. Accepts a comma-separated list and glob patterns. If not set parse all sites.")
 	cmd.Flags().BoolVarP(&commonFlags.ignoreVersion, "ignore-version", "", false, "Skip MACH composer version check")
 	cmd.Flags().StringVarP(&commonFlags.outputPath, "output-path", "", "deployments",
 		"Outputs path to store the generated files.")
@@ -38,11 +52,28 @@ func registerCommonFlags(cmd *cobra.Command) {
 	_ = cmd.RegisterFlagCompletionFunc("site", AutocompleteSiteName)
 }
 
-func preprocessCommonFlags(cmd *cobra.Command) {
-	if commonFlags.siteName != "" {
-		log.Warn().Msgf("Site option not implemented")
-	}
+// registerRunnerFlags registers the flags that determine how terraform is run, for the commands that run terraform
+// on the deployment graph
+func registerRunnerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&commonFlags.strategy, "strategy", "", string(runner.BatchStrategy),
+		"The strategy used to schedule nodes. Either 'batch' (run nodes per depth level) or 'dependency' (run a node as soon as its dependencies are done)")
+	cmd.Flags().StringVarP(&commonFlags.outputMode, "output-mode", "", "",
+		"How the terraform output of the components is written. Either 'interactive', 'prefixed' (every line is prefixed with the component) or 'grouped' (written at once when a component has finished). "+
+			"Defaults to 'interactive' with a single worker or when terraform asks for input, and 'prefixed' otherwise. Input is only possible in interactive mode. Full logs are always written to the logs directory of every component")
+
+	cmd.Flags().StringVarP(&commonFlags.terraformBin, "terraform-binary", "", "",
+		"Name or path of the terraform executable, for example tofu. Overrides the "+utils.TerraformBinaryEnv+" environment variable and the mach_composer.terraform.binary setting")
+
+	cmd.Flags().DurationVarP(&commonFlags.timeout, "timeout", "", 0,
+		"Maximum duration of the whole run, for example 1h. Running terraform commands are interrupted when it expires. Disabled by default")
+	cmd.Flags().DurationVarP(&commonFlags.nodeTimeout, "node-timeout", "", 0,
+		"Maximum duration of every terraform command run for a component, for example 15m. Can be overridden per component with the timeout setting. Disabled by default")
+
+	cmd.Flags().DurationVarP(&commonFlags.gracePeriod, "grace-period", "", utils.DefaultStopGracePeriod,
+		"Time terraform is given to exit and release its state lock after being interrupted, before it is killed")
+}
 
+func preprocessCommonFlags(cmd *cobra.Command) {
 	handleError(cmd.MarkFlagFilename("var-file", "yml", "yaml"))
 	handleError(cmd.MarkFlagFilename("file", "yml", "yaml"))
 
@@ -107,3 +138,102 @@ func loadConfig(cmd *cobra.Command, resolveVars bool) *config.MachConfig {
 
 	return cfg
 }
+
+// loadDeploymentGraph creates the deployment graph for the given config. If the site flag is set the graph is reduced
+// to the project node and the selected sites including all their descendants.
+func loadDeploymentGraph(cfg *config.MachConfig) (*graph.Graph, error) {
+	dg, err := graph.ToDeploymentGraph(cfg, commonFlags.outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if commonFlags.siteName != "" {
+		if err = graph.FilterSites(dg, graph.ParseSitePatterns(commonFlags.siteName)); err != nil {
+			return nil, err
+		}
+	}
+
+	return dg, nil
+}
+
+// selectTargets resolves the given component names to the nodes of the deployment graph that should be run. If no
+// components are given all nodes are run, which is indicated by an empty result.
+func selectTargets(dg *graph.Graph, components []string, opts *graph.SelectOptions) (graph.Vertices, error) {
+	if len(components) == 0 {
+		return nil, nil
+	}
+
+	return graph.SelectComponents(dg, components, opts)
+}
+
+// newGraphRunner creates the runner used to execute the terraform commands on the deployment graph. Input indicates
+// whether terraform asks for input, which is only possible with interactive output.
+func newGraphRunner(ctx context.Context, cfg *config.MachConfig, input bool) (*runner.GraphRunner, error) {
+	strategy, err := runner.ParseStrategy(commonFlags.strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	outputMode, err := runner.ParseOutputMode(commonFlags.outputMode, commonFlags.workers, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = configureTerraform(ctx, cfg); err != nil {
+		return nil, err
+	}
+
+	hashHandler, err := hash.Factory(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	retryPolicies, err := runner.NewRetryPolicies(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	protectedResources, err := runner.NewProtectedResources(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := runner.NewGraphRunner(
+		batcher.NaiveBatchFunc(),
+		hashHandler,
+		commonFlags.workers,
+		strategy,
+	)
+	r.SetOutputMode(outputMode)
+	r.SetRetryPolicies(retryPolicies)
+	r.SetProtectedResources(protectedResources)
+	r.SetTimeouts(commonFlags.timeout, commonFlags.nodeTimeout)
+	utils.SetStopGracePeriod(commonFlags.gracePeriod)
+	return r, nil
+}
+
+// configureTerraform selects the terraform executable, in order of precedence from the flag, the environment variable
+// or the config, and verifies its version if a constraint is configured
+func configureTerraform(ctx context.Context, cfg *config.MachConfig) error {
+	binary := commonFlags.terraformBin
+	if binary == "" {
+		binary = os.Getenv(utils.TerraformBinaryEnv)
+	}
+	if binary == "" {
+		binary = cfg.MachComposer.Terraform.Binary
+	}
+	utils.SetTerraformBinary(binary)
+
+	if constraint := cfg.MachComposer.Terraform.RequiredVersion; constraint != "" {
+		return utils.CheckTerraformVersion(ctx, constraint)
+	}
+	return nil
+}
+
+// suppressInfoLogs raises the log level to warnings, so machine-readable output written to stdout is not mixed with
+// informational messages. Warnings and errors are written to stderr and remain visible.
+func suppressInfoLogs() {
+	if zerolog.GlobalLevel() < zerolog.WarnLevel {
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/spf13/cobra"
+
+	"github.com/mach-composer/mach-composer-cli/internal/generator"
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+)
+
+// driftExitCode is the exit code when drift is detected, matching terraform plan -detailed-exitcode
+const driftExitCode = 2
+
+var driftFlags struct {
+	forceInit        bool
+	components       []string
+	withDependencies bool
+	withDependents   bool
+	refreshOnly      bool
+}
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Detect drift between the deployed resources and the terraform state.",
+	Long: `
+Detect drift between the deployed resources and the terraform state of every component.
+
+Every component is planned regardless of change detection, by default with terraform plan -refresh-only. Saved plans
+and the stored hashes are not modified. The exit code is 0 if no drift was found, 2 if drift was found in at least one
+component, and 1 if drift detection failed for a component.
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return driftFunc(cmd, args)
+	},
+}
+
+func init() {
+	registerCommonFlags(driftCmd)
+	registerRunnerFlags(driftCmd)
+	driftCmd.Flags().BoolVarP(&driftFlags.forceInit, "force-init", "", false, "Force terraform initialization. By default mach-composer will reuse existing terraform resources")
+	driftCmd.Flags().StringArrayVarP(&driftFlags.components, "component", "c", nil, "Component to run. Can be repeated to select multiple components. If not set run all components.")
+	driftCmd.Flags().BoolVarP(&driftFlags.withDependencies, "with-dependencies", "", false, "Also run the components the selected components depend on")
+	driftCmd.Flags().BoolVarP(&driftFlags.withDependents, "with-dependents", "", false, "Also run the components that depend on the selected components")
+	driftCmd.Flags().BoolVarP(&driftFlags.refreshOnly, "refresh-only", "", true, "Only detect changes made outside of terraform. If disabled, changes in the configuration are reported as drift as well")
+
+	_ = driftCmd.RegisterFlagCompletionFunc("component", AutocompleteComponentName)
+}
+
+func driftFunc(cmd *cobra.Command, _ []string) error {
+	cfg := loadConfig(cmd, true)
+	defer cfg.Close()
+	ctx := cmd.Context()
+
+	dg, err := loadDeploymentGraph(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = generator.Write(ctx, cfg, dg, nil)
+	if err != nil {
+		return err
+	}
+
+	targets, err := selectTargets(dg, driftFlags.components, &graph.SelectOptions{
+		WithDependencies: driftFlags.withDependencies,
+		WithDependents:   driftFlags.withDependents,
+	})
+	if err != nil {
+		return err
+	}
+
+	r, err := newGraphRunner(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
+
+	report, err := r.TerraformDrift(ctx, dg, &runner.DriftOptions{
+		ForceInit:   driftFlags.forceInit,
+		RefreshOnly: driftFlags.refreshOnly,
+		Targets:     targets,
+	})
+	if err != nil {
+		return err
+	}
+
+	report.Write(os.Stdout)
+
+	switch {
+	case report.HasErrors():
+		return cli.NewExitCodeError("drift detection failed for one or more components", 1)
+	case report.HasDrift():
+		return cli.NewExitCodeError("drift detected", driftExitCode)
+	}
+	return nil
+}
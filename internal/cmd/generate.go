@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/spf13/cobra"
 
 	"github.com/mach-composer/mach-composer-cli/internal/generator"
@@ -26,7 +25,7 @@ func generateFunc(cmd *cobra.Command) error {
 	cfg := loadConfig(cmd, true)
 	defer cfg.Close()
 
-	gd, err := graph.ToDeploymentGraph(cfg, commonFlags.outputPath)
+	gd, err := loadDeploymentGraph(cfg)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mach-composer/mach-composer-cli/internal/generator"
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+)
+
+var importFlags struct {
+	forceInit bool
+	component string
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import --site <site> --component <component> <address> <id>",
+	Short: "Import an existing resource into the state of a component.",
+	Long: `
+Import an existing resource into the state of a component.
+
+The address is relative to the component. If the component is deployed as part of its site, the resource is imported
+into the site state and the address is prefixed with module.<component>.
+`,
+	Args: cobra.ExactArgs(2),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return importFunc(cmd, args)
+	},
+}
+
+func init() {
+	registerCommonFlags(importCmd)
+	registerRunnerFlags(importCmd)
+	importCmd.Flags().BoolVarP(&importFlags.forceInit, "force-init", "", false, "Force terraform initialization. By default mach-composer will reuse existing terraform resources")
+	registerComponentStateFlags(importCmd, &importFlags.component)
+}
+
+// registerComponentStateFlags registers the flag to select the component whose state is modified. The site is
+// selected with the common site flag.
+func registerComponentStateFlags(cmd *cobra.Command, component *string) {
+	cmd.Flags().StringVarP(component, "component", "c", "", "Component whose state is modified")
+	_ = cmd.MarkFlagRequired("component")
+	_ = cmd.MarkFlagRequired("site")
+	_ = cmd.RegisterFlagCompletionFunc("component", AutocompleteComponentName)
+}
+
+// componentStateOptions validates that a single site is selected
+func componentStateOptions(component string, forceInit bool) (*runner.ComponentStateOptions, error) {
+	if strings.ContainsAny(commonFlags.siteName, ",*?[") {
+		return nil, fmt.Errorf("a single site must be selected, got %s", commonFlags.siteName)
+	}
+
+	return &runner.ComponentStateOptions{
+		ForceInit: forceInit,
+		Site:      commonFlags.siteName,
+		Component: component,
+	}, nil
+}
+
+func importFunc(cmd *cobra.Command, args []string) error {
+	opts, err := componentStateOptions(importFlags.component, importFlags.forceInit)
+	if err != nil {
+		return err
+	}
+
+	cfg := loadConfig(cmd, true)
+	defer cfg.Close()
+	ctx := cmd.Context()
+
+	dg, err := loadDeploymentGraph(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = generator.Write(ctx, cfg, dg, nil)
+	if err != nil {
+		return err
+	}
+
+	r, err := newGraphRunner(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
+
+	return r.TerraformImport(ctx, dg, &runner.ImportOptions{
+		ComponentStateOptions: *opts,
+		Address:               args[0],
+		ID:                    args[1],
+	})
+}
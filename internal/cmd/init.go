@@ -1,14 +1,10 @@
 package cmd
 
 import (
-	"github.com/mach-composer/mach-composer-cli/internal/batcher"
-	"github.com/mach-composer/mach-composer-cli/internal/graph"
-	"github.com/mach-composer/mach-composer-cli/internal/hash"
 	"github.com/spf13/cobra"
 
 	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/generator"
-	"github.com/mach-composer/mach-composer-cli/internal/runner"
 )
 
 var initCmd = &cobra.Command{
@@ -28,6 +24,7 @@ var initCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(initCmd)
+	registerRunnerFlags(initCmd)
 }
 
 func initFunc(cmd *cobra.Command, _ []string) error {
@@ -35,7 +32,7 @@ func initFunc(cmd *cobra.Command, _ []string) error {
 	defer cfg.Close()
 	ctx := cmd.Context()
 
-	dg, err := graph.ToDeploymentGraph(cfg, commonFlags.outputPath)
+	dg, err := loadDeploymentGraph(cfg)
 	if err != nil {
 		return err
 	}
@@ -45,11 +42,10 @@ func initFunc(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	r := runner.NewGraphRunner(
-		batcher.NaiveBatchFunc(),
-		hash.Factory(cfg),
-		commonFlags.workers,
-	)
+	r, err := newGraphRunner(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
 
 	return r.TerraformInit(ctx, dg)
 }
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/mach-composer/mach-composer-cli/internal/generator"
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+)
+
+var migrateStateFlags struct {
+	forceInit  bool
+	components []string
+	dryRun     bool
+}
+
+var migrateStateCmd = &cobra.Command{
+	Use:   "migrate-state",
+	Short: "Migrate the state of components whose deployment type changed.",
+	Long: `
+Migrate the state of components whose deployment type changed.
+
+The deployment type of every component is recorded when it is applied. When a component is changed from a site
+deployment to a site-component deployment, its resources are moved from the site state to the state of the component.
+Use --dry-run to list the resources that would be moved without modifying any state.
+`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return migrateStateFunc(cmd, args)
+	},
+}
+
+func init() {
+	registerCommonFlags(migrateStateCmd)
+	registerRunnerFlags(migrateStateCmd)
+	migrateStateCmd.Flags().BoolVarP(&migrateStateFlags.forceInit, "force-init", "", false, "Force terraform initialization. By default mach-composer will reuse existing terraform resources")
+	migrateStateCmd.Flags().StringArrayVarP(&migrateStateFlags.components, "component", "c", nil, "Only migrate the given components. Can be specified multiple times")
+	migrateStateCmd.Flags().BoolVarP(&migrateStateFlags.dryRun, "dry-run", "", false, "Only list the resources that would be moved")
+	_ = migrateStateCmd.RegisterFlagCompletionFunc("component", AutocompleteComponentName)
+}
+
+func migrateStateFunc(cmd *cobra.Command, _ []string) error {
+	cfg := loadConfig(cmd, true)
+	defer cfg.Close()
+	ctx := cmd.Context()
+
+	dg, err := loadDeploymentGraph(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = generator.Write(ctx, cfg, dg, nil)
+	if err != nil {
+		return err
+	}
+
+	r, err := newGraphRunner(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
+
+	return r.MigrateState(ctx, dg, &runner.MigrateStateOptions{
+		ForceInit:  migrateStateFlags.forceInit,
+		Components: migrateStateFlags.components,
+		DryRun:     migrateStateFlags.dryRun,
+	})
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mach-composer/mach-composer-cli/internal/generator"
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+)
+
+var outputFlags struct {
+	components    []string
+	format        string
+	showSensitive bool
+}
+
+var outputCmd = &cobra.Command{
+	Use:   "output",
+	Short: "Show the terraform outputs of the components.",
+	Long: `
+Show the terraform outputs of the components as a single document, keyed by site and component.
+
+The outputs are read from the terraform state, so terraform must be initialized beforehand. The values of the outputs
+that are marked sensitive in the module of the component are redacted unless --show-sensitive is set.
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return outputFunc(cmd, args)
+	},
+}
+
+func init() {
+	registerCommonFlags(outputCmd)
+	registerRunnerFlags(outputCmd)
+	outputCmd.Flags().StringArrayVarP(&outputFlags.components, "component", "c", nil, "Component to show the outputs of. Can be repeated to select multiple components. If not set show all components.")
+	outputCmd.Flags().StringVarP(&outputFlags.format, "format", "", "json", "Output format. One of: json, yaml, dotenv")
+	outputCmd.Flags().BoolVarP(&outputFlags.showSensitive, "show-sensitive", "", false, "Show the values of sensitive outputs instead of redacting them")
+
+	_ = outputCmd.RegisterFlagCompletionFunc("component", AutocompleteComponentName)
+}
+
+func outputFunc(cmd *cobra.Command, _ []string) error {
+	var write func(doc runner.OutputsDocument) error
+	switch outputFlags.format {
+	case "json":
+		write = func(doc runner.OutputsDocument) error { return doc.WriteJSON(os.Stdout) }
+	case "yaml":
+		write = func(doc runner.OutputsDocument) error { return doc.WriteYAML(os.Stdout) }
+	case "dotenv":
+		write = func(doc runner.OutputsDocument) error { return doc.WriteDotenv(os.Stdout) }
+	default:
+		return fmt.Errorf("invalid output format %s, must be one of: json, yaml, dotenv", outputFlags.format)
+	}
+
+	suppressInfoLogs()
+
+	cfg := loadConfig(cmd, true)
+	defer cfg.Close()
+	ctx := cmd.Context()
+
+	dg, err := loadDeploymentGraph(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = generator.Write(ctx, cfg, dg, nil)
+	if err != nil {
+		return err
+	}
+
+	r, err := newGraphRunner(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
+
+	doc, err := r.TerraformOutputs(ctx, dg, &runner.OutputsOptions{
+		Components:    outputFlags.components,
+		ShowSensitive: outputFlags.showSensitive,
+	})
+	if err != nil {
+		return err
+	}
+
+	return write(doc)
+}
@@ -1,10 +1,7 @@
 package cmd
 
 import (
-	"github.com/mach-composer/mach-composer-cli/internal/batcher"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
-	"github.com/mach-composer/mach-composer-cli/internal/hash"
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
 	"github.com/mach-composer/mach-composer-cli/internal/generator"
@@ -14,8 +11,12 @@ import (
 var planFlags struct {
 	forceInit             bool
 	components            []string
+	withDependencies      bool
+	withDependents        bool
 	lock                  bool
 	ignoreChangeDetection bool
+	keepGoing             bool
+	dryRun                bool
 }
 
 var planCmd = &cobra.Command{
@@ -32,22 +33,26 @@ var planCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(planCmd)
+	registerRunnerFlags(planCmd)
 	planCmd.Flags().BoolVarP(&planFlags.forceInit, "force-init", "", false, "Force terraform initialization. By default mach-composer will reuse existing terraform resources")
-	planCmd.Flags().StringArrayVarP(&planFlags.components, "component", "c", nil, "")
+	planCmd.Flags().StringArrayVarP(&planFlags.components, "component", "c", nil, "Component to run. Can be repeated to select multiple components. If not set run all components.")
+	planCmd.Flags().BoolVarP(&planFlags.withDependencies, "with-dependencies", "", false, "Also run the components the selected components depend on")
+	planCmd.Flags().BoolVarP(&planFlags.withDependents, "with-dependents", "", false, "Also run the components that depend on the selected components")
 	planCmd.Flags().BoolVarP(&planFlags.lock, "lock", "", true, "Acquire a lock on the state file before running terraform plan")
 	planCmd.Flags().BoolVarP(&planFlags.ignoreChangeDetection, "ignore-change-detection", "", false, "Ignore change detection to run even if the components are considered up to date")
+	planCmd.Flags().BoolVarP(&planFlags.keepGoing, "keep-going", "", false, "Continue running the components that do not depend on a failed component, and report the outcome of every component at the end")
+
+	planCmd.Flags().BoolVarP(&planFlags.dryRun, "dry-run", "", false, "Show the batches, the components that are skipped and the terraform commands that would be run, without running them")
+
+	_ = planCmd.RegisterFlagCompletionFunc("component", AutocompleteComponentName)
 }
 
 func planFunc(cmd *cobra.Command, _ []string) error {
-	if len(planFlags.components) > 0 {
-		log.Warn().Msgf("Components option not implemented")
-	}
-
 	cfg := loadConfig(cmd, true)
 	defer cfg.Close()
 	ctx := cmd.Context()
 
-	dg, err := graph.ToDeploymentGraph(cfg, commonFlags.outputPath)
+	dg, err := loadDeploymentGraph(cfg)
 	if err != nil {
 		return err
 	}
@@ -57,15 +62,25 @@ func planFunc(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	r := runner.NewGraphRunner(
-		batcher.NaiveBatchFunc(),
-		hash.Factory(cfg),
-		commonFlags.workers,
-	)
+	targets, err := selectTargets(dg, planFlags.components, &graph.SelectOptions{
+		WithDependencies: planFlags.withDependencies,
+		WithDependents:   planFlags.withDependents,
+	})
+	if err != nil {
+		return err
+	}
+
+	r, err := newGraphRunner(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
 
 	return r.TerraformPlan(ctx, dg, &runner.PlanOptions{
 		ForceInit:             planFlags.forceInit,
 		Lock:                  planFlags.lock,
 		IgnoreChangeDetection: planFlags.ignoreChangeDetection,
+		KeepGoing:             planFlags.keepGoing,
+		DryRun:                planFlags.dryRun,
+		Targets:               targets,
 	})
 }
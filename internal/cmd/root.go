@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/cmd/cloudcmd"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -44,9 +45,10 @@ var (
 			log.Logger = logger
 
 			ctx := logger.WithContext(cmd.Context())
-			ctx, cancel := context.WithCancel(ctx)
+			ctx, cancel := context.WithCancelCause(ctx)
 
-			// Register a signal handler to cancel the current context
+			// Register a signal handler to cancel the current context. Running terraform commands are interrupted and
+			// given the grace period to release their state locks.
 			c := make(chan os.Signal, 1)
 			signal.Notify(c, os.Interrupt)
 
@@ -54,7 +56,7 @@ var (
 				select {
 				case <-c:
 					log.Info().Msg("Exiting...")
-					cancel()
+					cancel(utils.ErrInterrupted)
 				case <-ctx.Done():
 				}
 			}()
@@ -70,12 +72,18 @@ func init() {
 	RootCmd.AddCommand(applyCmd)
 	RootCmd.AddCommand(cloudcmd.CloudCmd)
 	RootCmd.AddCommand(componentsCmd)
+	RootCmd.AddCommand(driftCmd)
 	RootCmd.AddCommand(generateCmd)
+	RootCmd.AddCommand(importCmd)
 	RootCmd.AddCommand(initCmd)
+	RootCmd.AddCommand(migrateStateCmd)
+	RootCmd.AddCommand(outputCmd)
 	RootCmd.AddCommand(planCmd)
 	RootCmd.AddCommand(schemaCmd)
 	RootCmd.AddCommand(showPlanCmd)
 	RootCmd.AddCommand(sitesCmd)
+	RootCmd.AddCommand(stateCmd)
+	RootCmd.AddCommand(statusCmd)
 	RootCmd.AddCommand(updateCmd)
 	RootCmd.AddCommand(terraformCmd)
 	RootCmd.AddCommand(versionCmd)
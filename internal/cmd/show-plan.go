@@ -1,9 +1,6 @@
 package cmd
 
 import (
-	"github.com/mach-composer/mach-composer-cli/internal/batcher"
-	"github.com/mach-composer/mach-composer-cli/internal/graph"
-	"github.com/mach-composer/mach-composer-cli/internal/hash"
 	"github.com/spf13/cobra"
 
 	"github.com/mach-composer/mach-composer-cli/internal/runner"
@@ -13,6 +10,7 @@ var showPlanFlags struct {
 	forceInit             bool
 	noColor               bool
 	ignoreChangeDetection bool
+	json                  bool
 }
 
 var showPlanCmd = &cobra.Command{
@@ -29,31 +27,38 @@ var showPlanCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(showPlanCmd)
+	registerRunnerFlags(showPlanCmd)
 	showPlanCmd.Flags().BoolVarP(&showPlanFlags.forceInit, "force-init", "", false, "Force terraform initialization. By default mach-composer will reuse existing terraform resources")
 	showPlanCmd.Flags().BoolVarP(&showPlanFlags.noColor, "no-color", "", false, "Disable color output")
 	showPlanCmd.Flags().BoolVarP(&showPlanFlags.ignoreChangeDetection, "ignore-change-detection", "", false,
 		"Ignore change detection to run even if the components are considered up to date")
+	showPlanCmd.Flags().BoolVarP(&showPlanFlags.json, "json", "", false,
+		"Output a single JSON document with the resource changes of all components. Requires terraform to be initialized")
 }
 
 func showPlanFunc(cmd *cobra.Command, _ []string) error {
+	if showPlanFlags.json {
+		suppressInfoLogs()
+	}
+
 	cfg := loadConfig(cmd, true)
 	defer cfg.Close()
 	ctx := cmd.Context()
 
-	dg, err := graph.ToDeploymentGraph(cfg, commonFlags.outputPath)
+	dg, err := loadDeploymentGraph(cfg)
 	if err != nil {
 		return err
 	}
 
-	r := runner.NewGraphRunner(
-		batcher.NaiveBatchFunc(),
-		hash.Factory(cfg),
-		commonFlags.workers,
-	)
+	r, err := newGraphRunner(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
 
 	return r.TerraformShow(ctx, dg, &runner.ShowPlanOptions{
 		ForceInit:             showPlanFlags.forceInit,
 		NoColor:               showPlanFlags.noColor,
 		IgnoreChangeDetection: showPlanFlags.ignoreChangeDetection,
+		JSON:                  showPlanFlags.json,
 	})
 }
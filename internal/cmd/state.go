@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mach-composer/mach-composer-cli/internal/generator"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+)
+
+var stateFlags struct {
+	forceInit bool
+	component string
+	dryRun    bool
+}
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Modify the terraform state of a component.",
+	Long: `
+Modify the terraform state of a component.
+
+Addresses are relative to the component. If the component is deployed as part of its site, the site state is modified
+and the addresses are prefixed with module.<component>.
+`,
+}
+
+var stateMvCmd = &cobra.Command{
+	Use:   "mv --site <site> --component <component> <source> <destination>",
+	Short: "Move a resource within the state of a component.",
+	Args:  cobra.ExactArgs(2),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stateMvFunc(cmd, args)
+	},
+}
+
+var stateRmCmd = &cobra.Command{
+	Use:   "rm --site <site> --component <component> <address>...",
+	Short: "Remove resources from the state of a component, without destroying them.",
+	Args:  cobra.MinimumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stateRmFunc(cmd, args)
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{stateMvCmd, stateRmCmd} {
+		registerCommonFlags(cmd)
+		registerRunnerFlags(cmd)
+		cmd.Flags().BoolVarP(&stateFlags.forceInit, "force-init", "", false, "Force terraform initialization. By default mach-composer will reuse existing terraform resources")
+		cmd.Flags().BoolVarP(&stateFlags.dryRun, "dry-run", "", false, "Only list the resources that would be modified")
+		registerComponentStateFlags(cmd, &stateFlags.component)
+		stateCmd.AddCommand(cmd)
+	}
+}
+
+// runStateCommand prepares the graph and runner, and runs the function to modify the state of the selected component
+func runStateCommand(cmd *cobra.Command, f func(ctx context.Context, dg *graph.Graph, r *runner.GraphRunner, opts *runner.ComponentStateOptions) error) error {
+	opts, err := componentStateOptions(stateFlags.component, stateFlags.forceInit)
+	if err != nil {
+		return err
+	}
+
+	cfg := loadConfig(cmd, true)
+	defer cfg.Close()
+	ctx := cmd.Context()
+
+	dg, err := loadDeploymentGraph(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = generator.Write(ctx, cfg, dg, nil)
+	if err != nil {
+		return err
+	}
+
+	r, err := newGraphRunner(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
+
+	return f(ctx, dg, r, opts)
+}
+
+func stateMvFunc(cmd *cobra.Command, args []string) error {
+	return runStateCommand(cmd, func(ctx context.Context, dg *graph.Graph, r *runner.GraphRunner, opts *runner.ComponentStateOptions) error {
+		return r.TerraformStateMove(ctx, dg, &runner.StateMoveOptions{
+			ComponentStateOptions: *opts,
+			Source:                args[0],
+			Destination:           args[1],
+			DryRun:                stateFlags.dryRun,
+		})
+	})
+}
+
+func stateRmFunc(cmd *cobra.Command, args []string) error {
+	return runStateCommand(cmd, func(ctx context.Context, dg *graph.Graph, r *runner.GraphRunner, opts *runner.ComponentStateOptions) error {
+		return r.TerraformStateRemove(ctx, dg, &runner.StateRemoveOptions{
+			ComponentStateOptions: *opts,
+			Addresses:             args,
+			DryRun:                stateFlags.dryRun,
+		})
+	})
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/generator"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/runner"
+)
+
+var statusFlags struct {
+	output string
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the change detection state of every node.",
+	Long: `
+Show the change detection state of every node, without running terraform.
+
+For every node the stored hash is compared to the hash of the current configuration. Nodes are considered changed
+when their own configuration changed, when one of their parents changed, or when they have never been deployed.
+Referenced outputs of upstream components are not read from the terraform state; instead they are assumed to be
+unchanged since the last deployment.
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		preprocessCommonFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return statusFunc(cmd, args)
+	},
+}
+
+func init() {
+	registerCommonFlags(statusCmd)
+	statusCmd.Flags().StringVarP(&statusFlags.output, "output", "", "table", "Output format. One of: table, json")
+}
+
+func statusFunc(cmd *cobra.Command, _ []string) error {
+	if statusFlags.output != "table" && statusFlags.output != "json" {
+		return fmt.Errorf("invalid output format %s, must be one of: table, json", statusFlags.output)
+	}
+
+	if statusFlags.output == "json" {
+		suppressInfoLogs()
+	}
+
+	cfg := loadConfig(cmd, true)
+	defer cfg.Close()
+	ctx := cmd.Context()
+
+	dg, err := loadDeploymentGraph(cfg)
+	if err != nil {
+		return err
+	}
+
+	// The generated files are part of the hashes, so they need to be up to date
+	if err = generator.Write(ctx, cfg, dg, nil); err != nil {
+		return err
+	}
+
+	// Only the stored hashes are needed, so terraform is not configured and its version is not verified
+	hashHandler, err := hash.Factory(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	r := runner.NewGraphRunner(batcher.NaiveBatchFunc(), hashHandler, commonFlags.workers, runner.BatchStrategy)
+
+	report, err := r.Status(ctx, dg)
+	if err != nil {
+		return err
+	}
+
+	if statusFlags.output == "json" {
+		return report.WriteJSON(os.Stdout)
+	}
+
+	report.WriteTable(os.Stdout)
+	return nil
+}
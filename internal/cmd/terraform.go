@@ -1,9 +1,6 @@
 package cmd
 
 import (
-	"github.com/mach-composer/mach-composer-cli/internal/batcher"
-	"github.com/mach-composer/mach-composer-cli/internal/graph"
-	"github.com/mach-composer/mach-composer-cli/internal/hash"
 	"github.com/spf13/cobra"
 
 	"github.com/mach-composer/mach-composer-cli/internal/runner"
@@ -27,6 +24,7 @@ var terraformCmd = &cobra.Command{
 
 func init() {
 	registerCommonFlags(terraformCmd)
+	registerRunnerFlags(terraformCmd)
 	terraformCmd.Flags().BoolVarP(&terraformFlags.ignoreChangeDetection, "ignore-change-detection", "", true,
 		"Ignore change detection to run even if the components are considered up to date. Per default the proxy will ignore change detection")
 }
@@ -36,16 +34,15 @@ func terraformFunc(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	defer cfg.Close()
 
-	dg, err := graph.ToDeploymentGraph(cfg, commonFlags.outputPath)
+	dg, err := loadDeploymentGraph(cfg)
 	if err != nil {
 		return err
 	}
 
-	r := runner.NewGraphRunner(
-		batcher.NaiveBatchFunc(),
-		hash.Factory(cfg),
-		commonFlags.workers,
-	)
+	r, err := newGraphRunner(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
 
 	return r.TerraformProxy(ctx, dg, &runner.ProxyOptions{
 		Command:               args,
@@ -7,6 +7,7 @@ import (
 	"github.com/mach-composer/mach-composer-plugin-sdk/v2/schema"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
+	"time"
 )
 
 type ComponentConfig struct {
@@ -17,6 +18,13 @@ type ComponentConfig struct {
 	Branch       string            `yaml:"branch"`
 	Integrations []string          `yaml:"integrations"`
 	Endpoints    map[string]string `yaml:"endpoints"`
+	Retry        *RetryConfig      `yaml:"retry"`
+	// Timeout is the maximum duration of every terraform command run for the component. It overrides the node timeout
+	// of the run
+	Timeout time.Duration `yaml:"timeout"`
+	// ProtectedResources are patterns of resource types or addresses that may not be deleted or replaced by apply. They
+	// only apply to the resources of the component, in addition to the global patterns
+	ProtectedResources []string `yaml:"protected_resources"`
 }
 
 func parseComponentsNode(cfg *MachConfig, node *yaml.Node) error {
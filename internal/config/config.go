@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/mach-composer/mach-composer-cli/internal/plugins"
 	"github.com/mach-composer/mach-composer-cli/internal/state"
 	"github.com/mach-composer/mcc-sdk-go/mccsdk"
@@ -42,6 +44,11 @@ type MachComposer struct {
 	Plugins       map[string]MachPluginConfig `yaml:"plugins"`
 	Cloud         MachComposerCloud           `yaml:"cloud"`
 	Deployment    Deployment                  `yaml:"deployment"`
+	Terraform     MachComposerTerraform       `yaml:"terraform"`
+	Retry         *RetryConfig                `yaml:"retry"`
+	// ProtectedResources are patterns of resource types or addresses that may not be deleted or replaced by apply
+	// unless explicitly allowed
+	ProtectedResources []string `yaml:"protected_resources"`
 }
 
 func (mc *MachComposer) CloudEnabled() bool {
@@ -65,6 +72,23 @@ func (mcc *MachComposerCloud) Empty() bool {
 	return false
 }
 
+// MachComposerTerraform configures the terraform executable, which can also be a compatible one like OpenTofu
+type MachComposerTerraform struct {
+	Binary          string `yaml:"binary"`
+	RequiredVersion string `yaml:"required_version"`
+}
+
+// RetryConfig determines when a failed terraform command is retried. It can be set globally and per component, in
+// which case the fields set on the component take precedence.
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+	// RetryableErrors are regular expressions matched against the error output of the failed command. If empty, only
+	// known transient failures are retried
+	RetryableErrors []string `yaml:"retryable_errors"`
+}
+
 type MachPluginConfig struct {
 	Source  string `yaml:"source"`
 	Version string `yaml:"version"`
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"slices"
+	"strings"
 )
 
 type Type string
@@ -22,6 +23,17 @@ type Variable interface {
 	Type() Type
 	TransformValue(f TransformValueFunc) (any, error)
 	ReferencedComponents() []string
+	ReferencedOutputs() []OutputReference
+}
+
+// OutputReference is a reference to an output of another component, as used in `${component.<name>.<output>}`
+type OutputReference struct {
+	Component string
+	Output    string
+}
+
+func (r OutputReference) String() string {
+	return fmt.Sprintf("%s.%s", r.Component, r.Output)
 }
 
 type baseVariable struct {
@@ -110,3 +122,21 @@ func (vl *VariablesMap) ListReferencedComponents() []string {
 
 	return slices.Compact(references)
 }
+
+// ListReferencedOutputs returns the sorted, unique component outputs referenced by the variables
+func (vl *VariablesMap) ListReferencedOutputs() []OutputReference {
+	var references []OutputReference
+
+	for _, v := range *vl {
+		references = append(references, v.ReferencedOutputs()...)
+	}
+
+	return compactOutputReferences(references)
+}
+
+func compactOutputReferences(references []OutputReference) []OutputReference {
+	slices.SortFunc(references, func(a, b OutputReference) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return slices.Compact(references)
+}
@@ -21,6 +21,16 @@ func (v *MapVariable) ReferencedComponents() []string {
 	return slices.Compact(references)
 }
 
+func (v *MapVariable) ReferencedOutputs() []OutputReference {
+	var references []OutputReference
+
+	for _, element := range v.Elements {
+		references = append(references, element.ReferencedOutputs()...)
+	}
+
+	return compactOutputReferences(references)
+}
+
 func (v *MapVariable) TransformValue(f TransformValueFunc) (any, error) {
 	var data = make(map[string]any, len(v.Elements))
 	var err error
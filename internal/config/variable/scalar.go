@@ -15,20 +15,30 @@ type ScalarVariable struct {
 	baseVariable
 	Content    any
 	references []string
+	outputs    []OutputReference
 }
 
 func NewScalarVariable(content any) (*ScalarVariable, error) {
 	var references []string
+	var outputs []OutputReference
 	if s, ok := content.(string); ok {
-		parsedReferences, err := parseReferences(s)
+		parsedValues, err := parseValues(s)
 		if err != nil {
 			return nil, err
 		}
 
-		references = append(references, parsedReferences...)
+		for _, v := range parsedValues {
+			references = append(references, v[1])
+			outputs = append(outputs, OutputReference{Component: v[1], Output: v[2]})
+		}
 	}
 
-	return &ScalarVariable{baseVariable: baseVariable{typ: Scalar}, Content: content, references: references}, nil
+	return &ScalarVariable{
+		baseVariable: baseVariable{typ: Scalar},
+		Content:      content,
+		references:   references,
+		outputs:      outputs,
+	}, nil
 }
 
 func (v *ScalarVariable) TransformValue(f TransformValueFunc) (any, error) {
@@ -39,6 +49,10 @@ func (v *ScalarVariable) ReferencedComponents() []string {
 	return v.references
 }
 
+func (v *ScalarVariable) ReferencedOutputs() []OutputReference {
+	return v.outputs
+}
+
 func parseValues(v string) ([][]string, error) {
 	val := strings.TrimSpace(v)
 	matches := varComponentRegex.FindAllStringSubmatch(val, 20)
@@ -63,23 +77,6 @@ func parseValues(v string) ([][]string, error) {
 	return parsedValues, nil
 }
 
-func parseReferences(v string) ([]string, error) {
-	val, err := parseValues(v)
-	if err != nil {
-		return nil, err
-	}
-	if val == nil {
-		return nil, nil
-	}
-
-	var references []string
-	for _, v := range val {
-		references = append(references, v[1])
-	}
-
-	return references, nil
-}
-
 func ModuleTransformFunc() TransformValueFunc {
 	return func(value any) (any, error) {
 		val, ok := value.(string)
@@ -27,6 +27,26 @@ func TestNewScalarVariable(t *testing.T) {
 	}
 }
 
+func TestListReferencedOutputs(t *testing.T) {
+	vars := VariablesMap{
+		"a": MustCreateNewScalarVariable(t, "${component.foo.endpoint}"),
+		"b": NewSliceVariable([]Variable{
+			MustCreateNewScalarVariable(t, "${component.bar.other}"),
+			MustCreateNewScalarVariable(t, "${component.foo.endpoint}"),
+		}),
+		"c": NewMapVariable(map[string]Variable{
+			"nested": MustCreateNewScalarVariable(t, "${component.foo.nested.value}"),
+		}),
+		"d": MustCreateNewScalarVariable(t, "plain"),
+	}
+
+	assert.Equal(t, []OutputReference{
+		{Component: "bar", Output: "other"},
+		{Component: "foo", Output: "endpoint"},
+		{Component: "foo", Output: "nested.value"},
+	}, vars.ListReferencedOutputs())
+}
+
 func TestModuleTransformFunc(t *testing.T) {
 	type test struct {
 		input  string
@@ -21,6 +21,16 @@ func (v *SliceVariable) ReferencedComponents() []string {
 	return slices.Compact(references)
 }
 
+func (v *SliceVariable) ReferencedOutputs() []OutputReference {
+	var references []OutputReference
+
+	for _, element := range v.Elements {
+		references = append(references, element.ReferencedOutputs()...)
+	}
+
+	return compactOutputReferences(references)
+}
+
 func (v *SliceVariable) TransformValue(f TransformValueFunc) (any, error) {
 	var data = make([]any, 0, len(v.Elements))
 
@@ -101,13 +101,15 @@ func renderSiteComponentTerraformConfig(cfg *config.MachConfig, site *config.Sit
 	}
 
 	templateContext := struct {
-		Providers     []string
-		BackendConfig string
-		IncludeSOPS   bool
+		Providers       []string
+		BackendConfig   string
+		RequiredVersion string
+		IncludeSOPS     bool
 	}{
-		Providers:     providers,
-		BackendConfig: backendConfig,
-		IncludeSOPS:   cfg.Variables.HasEncrypted(site.Identifier),
+		Providers:       providers,
+		BackendConfig:   backendConfig,
+		RequiredVersion: cfg.MachComposer.Terraform.RequiredVersion,
+		IncludeSOPS:     cfg.Variables.HasEncrypted(site.Identifier),
 	}
 	return utils.RenderGoTemplate(string(tpl), templateContext)
 }
@@ -223,6 +225,8 @@ func renderRemoteSources(cfg *config.MachConfig, component *config.SiteComponent
 		links = append(links, key)
 	}
 
+	// Sort the links so the generated file is stable, as it is included in the hash of the component
+	slices.Sort(links)
 	links = slices.Compact(links)
 
 	var result []string
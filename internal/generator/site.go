@@ -81,13 +81,15 @@ func renderSiteTerraformConfig(cfg *config.MachConfig, site *config.SiteConfig)
 	}
 
 	templateContext := struct {
-		Providers     []string
-		BackendConfig string
-		IncludeSOPS   bool
+		Providers       []string
+		BackendConfig   string
+		RequiredVersion string
+		IncludeSOPS     bool
 	}{
-		Providers:     providers,
-		BackendConfig: backendConfig,
-		IncludeSOPS:   cfg.Variables.HasEncrypted(site.Identifier),
+		Providers:       providers,
+		BackendConfig:   backendConfig,
+		RequiredVersion: cfg.MachComposer.Terraform.RequiredVersion,
+		IncludeSOPS:     cfg.Variables.HasEncrypted(site.Identifier),
 	}
 	return utils.RenderGoTemplate(string(tpl), templateContext)
 }
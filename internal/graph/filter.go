@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/dominikbraun/graph"
+)
+
+// ParseSitePatterns splits a comma separated list of site identifiers or glob patterns into a list of patterns
+func ParseSitePatterns(value string) []string {
+	var patterns []string
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// FilterSites reduces the graph to the start node, the sites matching any of the given patterns and all the
+// descendants of those sites. Patterns are matched against the site identifier and may contain glob expressions
+// (for example `nl-*`). All nodes that are not selected are removed from the graph.
+func FilterSites(g *Graph, patterns []string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid site pattern %s: %w", p, err)
+		}
+	}
+
+	var keep = map[string]bool{g.StartNode.Path(): true}
+	for _, n := range g.Vertices() {
+		if n.Type() != SiteType || !matchesAny(n.Identifier(), patterns) {
+			continue
+		}
+
+		if err := graph.DFS(g.Graph, n.Path(), func(p string) bool {
+			keep[p] = true
+			return false
+		}); err != nil {
+			return err
+		}
+	}
+
+	if len(keep) == 1 {
+		return fmt.Errorf("no sites found matching %s", strings.Join(patterns, ","))
+	}
+
+	return retainNodes(g, keep)
+}
+
+func matchesAny(identifier string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, identifier); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// retainNodes removes all the nodes (and their edges) from the graph that are not part of the keep set
+func retainNodes(g *Graph, keep map[string]bool) error {
+	am, err := g.Graph.AdjacencyMap()
+	if err != nil {
+		return err
+	}
+
+	for source, edges := range am {
+		for target := range edges {
+			if keep[source] && keep[target] {
+				continue
+			}
+			if err = g.Graph.RemoveEdge(source, target); err != nil {
+				return err
+			}
+		}
+	}
+
+	for p := range am {
+		if keep[p] {
+			continue
+		}
+		if err = g.Graph.RemoveVertex(p); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range g.Vertices() {
+		v.resetGraph(g.Graph)
+	}
+
+	return nil
+}
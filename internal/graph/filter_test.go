@@ -0,0 +1,109 @@
+package graph
+
+import (
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func filterTestConfig() *config.MachConfig {
+	var sites []config.SiteConfig
+	for _, identifier := range []string{"nl-1", "nl-2", "de-1"} {
+		sites = append(sites, config.SiteConfig{
+			Identifier: identifier,
+			Deployment: &config.Deployment{
+				Type: config.DeploymentSite,
+			},
+			Components: []config.SiteComponentConfig{
+				{
+					Name: "component-1",
+					Deployment: &config.Deployment{
+						Type: config.DeploymentSiteComponent,
+					},
+				},
+				{
+					Name: "component-2",
+					Deployment: &config.Deployment{
+						Type: config.DeploymentSiteComponent,
+					},
+					DependsOn: []string{"component-1"},
+				},
+			},
+		})
+	}
+
+	return &config.MachConfig{
+		Filename: "main",
+		MachComposer: config.MachComposer{
+			Deployment: config.Deployment{
+				Type: config.DeploymentSite,
+			},
+		},
+		Sites: sites,
+	}
+}
+
+func TestParseSitePatterns(t *testing.T) {
+	assert.Equal(t, []string{"nl-1"}, ParseSitePatterns("nl-1"))
+	assert.Equal(t, []string{"nl-1", "de-*"}, ParseSitePatterns("nl-1, de-*,"))
+	assert.Nil(t, ParseSitePatterns(""))
+}
+
+func TestFilterSitesSingle(t *testing.T) {
+	g, err := ToDeploymentGraph(filterTestConfig(), "")
+	assert.NoError(t, err)
+
+	err = FilterSites(g, []string{"de-1"})
+	assert.NoError(t, err)
+
+	o, _ := g.Order()
+	assert.Equal(t, 4, o)
+
+	for _, p := range []string{"main", "main/de-1", "main/de-1/component-1", "main/de-1/component-2"} {
+		_, err := g.Vertex(p)
+		assert.NoError(t, err)
+	}
+
+	n, _ := g.Vertex("main/de-1/component-2")
+	parents, err := n.Parents()
+	assert.NoError(t, err)
+	assert.Len(t, parents, 1)
+	assert.Equal(t, "main/de-1/component-1", parents[0].Path())
+}
+
+func TestFilterSitesGlobAndList(t *testing.T) {
+	g, err := ToDeploymentGraph(filterTestConfig(), "")
+	assert.NoError(t, err)
+
+	err = FilterSites(g, []string{"nl-*"})
+	assert.NoError(t, err)
+
+	o, _ := g.Order()
+	assert.Equal(t, 7, o)
+
+	_, err = g.Vertex("main/de-1")
+	assert.Error(t, err)
+
+	g, err = ToDeploymentGraph(filterTestConfig(), "")
+	assert.NoError(t, err)
+
+	err = FilterSites(g, ParseSitePatterns("nl-1,de-1"))
+	assert.NoError(t, err)
+
+	o, _ = g.Order()
+	assert.Equal(t, 7, o)
+
+	_, err = g.Vertex("main/nl-2")
+	assert.Error(t, err)
+}
+
+func TestFilterSitesNoMatch(t *testing.T) {
+	g, err := ToDeploymentGraph(filterTestConfig(), "")
+	assert.NoError(t, err)
+
+	err = FilterSites(g, []string{"be-*"})
+	assert.Error(t, err)
+
+	err = FilterSites(g, []string{"[nl"})
+	assert.Error(t, err)
+}
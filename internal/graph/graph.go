@@ -3,6 +3,7 @@ package graph
 import (
 	"github.com/dominikbraun/graph"
 	"golang.org/x/exp/maps"
+	"strings"
 )
 
 type Graph struct {
@@ -47,3 +48,8 @@ func (g *Graph) Routes(source, target string) ([]Path, error) {
 
 	return routes, nil
 }
+
+// RelativePath returns the path of the node relative to the start node, for example `site-1/component-1`
+func (g *Graph) RelativePath(n Node) string {
+	return strings.TrimPrefix(n.Path(), g.StartNode.Path()+"/")
+}
@@ -4,8 +4,50 @@ import (
 	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/config/variable"
 	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"os"
+	"path/filepath"
 )
 
+// generatedFile is the file the generator writes for every deployable node
+const generatedFile = "main.tf"
+
+// hashGeneratedConfig returns the hash of the terraform file generated for the node, together with the resolved
+// versions of the plugins that rendered it. This covers all configuration that is not part of the component config itself, like
+// plugin configuration on the site or site component. An empty string is returned if the file is not generated yet.
+func hashGeneratedConfig(n Node) (string, error) {
+	content, err := os.ReadFile(filepath.Join(n.Path(), generatedFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var plugins map[string]string
+	if cfg := projectConfig(n); cfg != nil && cfg.Plugins != nil {
+		plugins = cfg.Plugins.Versions()
+	}
+
+	return utils.ComputeHash(struct {
+		Content string            `json:"content"`
+		Plugins map[string]string `json:"plugins"`
+	}{
+		Content: string(content),
+		Plugins: plugins,
+	})
+}
+
+// projectConfig returns the configuration of the project the node belongs to, or nil if it cannot be found
+func projectConfig(n Node) *config.MachConfig {
+	for n != nil {
+		if p, ok := n.(*Project); ok {
+			return p.ProjectConfig
+		}
+		n = n.Ancestor()
+	}
+	return nil
+}
+
 func HashSiteComponentConfig(sc config.SiteComponentConfig) (string, error) {
 	var err error
 	var tfHash string
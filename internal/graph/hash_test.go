@@ -3,7 +3,11 @@ package graph
 import (
 	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/config/variable"
+	"github.com/mach-composer/mach-composer-cli/internal/plugins"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -69,3 +73,97 @@ func TestHashSiteComponentConfigGithubSource(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "de87afc8419dcd29e3e8cbe2e47b5026593ac0975555fe3d0f341eb3e0cf5785", h)
 }
+
+func TestSiteComponentHashOutputsDigest(t *testing.T) {
+	val, _ := variable.NewScalarVariable("${component.other.endpoint}")
+
+	sc := NewSiteComponent(nil, "main/site-1/component-1", "component-1", config.DeploymentSiteComponent, nil,
+		config.SiteConfig{}, config.SiteComponentConfig{
+			Name:      "component-1",
+			Variables: variable.VariablesMap{"endpoint": val},
+			Definition: &config.ComponentConfig{
+				Name:   "component-1",
+				Source: "testdata/dirhash",
+			},
+		})
+
+	configHash, err := HashSiteComponentConfig(sc.SiteComponentConfig)
+	assert.NoError(t, err)
+
+	h, err := sc.Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, configHash, h, "hash without digest should equal the config hash")
+
+	sc.SetOutputsDigest("digest-1")
+	h1, err := sc.Hash()
+	assert.NoError(t, err)
+	assert.NotEqual(t, configHash, h1)
+
+	sc.SetOutputsDigest("digest-2")
+	h2, err := sc.Hash()
+	assert.NoError(t, err)
+	assert.NotEqual(t, h1, h2)
+
+	assert.Equal(t, []variable.OutputReference{{Component: "other", Output: "endpoint"}}, sc.ReferencedOutputs())
+}
+
+func TestHashGeneratedConfig(t *testing.T) {
+	dir := t.TempDir()
+	repo := plugins.NewPluginRepository()
+	require.NoError(t, repo.Add("aws", plugins.NewPluginV1Adapter(plugins.NewMockPluginV1())))
+	aws, err := repo.Get("aws")
+	require.NoError(t, err)
+	aws.Config = plugins.PluginConfig{Source: "mach-composer/aws", Version: "1.0.0"}
+
+	// The versions in the config are not necessarily the versions that are run
+	cfg := &config.MachConfig{Plugins: repo, MachComposer: config.MachComposer{Plugins: map[string]config.MachPluginConfig{
+		"aws": {Source: "mach-composer/aws", Version: "1.0.0"},
+	}}}
+
+	project := NewProject(nil, dir, "main", config.DeploymentSite, cfg)
+	site := NewSite(nil, filepath.Join(dir, "site-1"), "site-1", config.DeploymentSite, project, config.SiteConfig{})
+	sc := NewSiteComponent(nil, filepath.Join(dir, "site-1", "component-1"), "component-1",
+		config.DeploymentSiteComponent, site, config.SiteConfig{}, config.SiteComponentConfig{
+			Name: "component-1",
+			Definition: &config.ComponentConfig{
+				Name:   "component-1",
+				Source: "testdata/dirhash",
+			},
+		})
+
+	for _, n := range []Node{site, sc} {
+		h, err := hashGeneratedConfig(n)
+		assert.NoError(t, err)
+		assert.Equal(t, "", h, "no hash is expected when the file is not generated")
+
+		require.NoError(t, os.MkdirAll(n.Path(), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(n.Path(), "main.tf"), []byte(`provider "aws" {}`), 0600))
+	}
+
+	original, err := sc.Hash()
+	require.NoError(t, err)
+	originalSite, err := site.Hash()
+	require.NoError(t, err)
+
+	// Changing the generated file changes the hash
+	require.NoError(t, os.WriteFile(filepath.Join(sc.Path(), "main.tf"), []byte(`provider "aws" { region = "eu-west-1" }`), 0600))
+	changed, err := sc.Hash()
+	require.NoError(t, err)
+	assert.NotEqual(t, original, changed)
+
+	// Changing the plugin version in the config only does not change the hash
+	cfg.MachComposer.Plugins["aws"] = config.MachPluginConfig{Source: "mach-composer/aws", Version: "1.1.0"}
+	h, err := sc.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, changed, h)
+
+	// Changing the resolved plugin version changes the hash of both the site and the component
+	aws.Config.Version = "1.1.0"
+	h, err = sc.Hash()
+	require.NoError(t, err)
+	assert.NotEqual(t, changed, h)
+
+	h, err = site.Hash()
+	require.NoError(t, err)
+	assert.NotEqual(t, originalSite, h)
+}
@@ -2,6 +2,7 @@ package graph
 
 import (
 	"github.com/dominikbraun/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -79,6 +80,11 @@ func (n *NodeMock) Tainted() bool {
 	return n.tainted
 }
 
+func (n *NodeMock) DeploymentType() config.DeploymentType {
+	args := n.Called()
+	return args.Get(0).(config.DeploymentType)
+}
+
 func (n *NodeMock) Hash() (string, error) {
 	args := n.Called()
 	return args.String(0), args.Error(1)
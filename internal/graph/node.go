@@ -23,6 +23,9 @@ type Node interface {
 	//Type returns the type of the node
 	Type() Type
 
+	//DeploymentType returns how the node is deployed, either as part of the site or as a separate component
+	DeploymentType() config.DeploymentType
+
 	//Ancestor returns the ancestor of the node. The ancestor is specific to the type of the node. For example,
 	//a site will have the project as ancestor, a site component will have the site as ancestor,
 	//and project will have no ancestor
@@ -101,6 +104,10 @@ func (n *baseNode) Type() Type {
 	return n.typ
 }
 
+func (n *baseNode) DeploymentType() config.DeploymentType {
+	return n.deploymentType
+}
+
 func (n *baseNode) Ancestor() Node {
 	return n.ancestor
 }
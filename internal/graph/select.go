@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dominikbraun/graph"
+	"github.com/rs/zerolog/log"
+)
+
+type SelectOptions struct {
+	// WithDependencies also selects all the nodes the selected components depend on
+	WithDependencies bool
+	// WithDependents also selects all the nodes that depend on the selected components
+	WithDependents bool
+}
+
+// SelectComponents returns the nodes that belong to the given component names. Components that are not deployed
+// independently resolve to the site node they are part of. Depending on the options the selection is expanded with
+// the ancestors and/or descendants of the selected nodes. The start node is never part of the selection.
+func SelectComponents(g *Graph, names []string, opts *SelectOptions) (Vertices, error) {
+	if opts == nil {
+		opts = &SelectOptions{}
+	}
+
+	var selected = map[string]Node{}
+	for _, name := range names {
+		nodes := findComponentNodes(g, name)
+		if len(nodes) == 0 {
+			return nil, fmt.Errorf("component %s not found", name)
+		}
+		for _, n := range nodes {
+			selected[n.Path()] = n
+		}
+	}
+
+	if opts.WithDependencies {
+		pm, err := g.PredecessorMap()
+		if err != nil {
+			return nil, err
+		}
+
+		var queue []string
+		for p := range selected {
+			queue = append(queue, p)
+		}
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			for source := range pm[p] {
+				if _, ok := selected[source]; ok {
+					continue
+				}
+				n, err := g.Vertex(source)
+				if err != nil {
+					return nil, err
+				}
+				selected[source] = n
+				queue = append(queue, source)
+			}
+		}
+	}
+
+	if opts.WithDependents {
+		var roots []string
+		for p := range selected {
+			roots = append(roots, p)
+		}
+		for _, root := range roots {
+			if err := graph.DFS(g.Graph, root, func(p string) bool {
+				n, _ := g.Vertex(p)
+				selected[p] = n
+				return false
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	delete(selected, g.StartNode.Path())
+
+	var result Vertices
+	for _, n := range selected {
+		result = append(result, n)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path() < result[j].Path()
+	})
+
+	return result, nil
+}
+
+func findComponentNodes(g *Graph, name string) []Node {
+	var nodes []Node
+	for _, n := range g.Vertices() {
+		switch v := n.(type) {
+		case *SiteComponent:
+			if v.Identifier() == name {
+				nodes = append(nodes, v)
+			}
+		case *Site:
+			for _, nested := range v.NestedNodes {
+				if nested.Identifier() == name {
+					log.Info().Msgf("Component %s is deployed as part of site %s; selecting the site instead",
+						name, v.Identifier())
+					nodes = append(nodes, v)
+					break
+				}
+			}
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func selectTestConfig() *config.MachConfig {
+	return &config.MachConfig{
+		Filename: "main",
+		MachComposer: config.MachComposer{
+			Deployment: config.Deployment{
+				Type: config.DeploymentSiteComponent,
+			},
+		},
+		Sites: []config.SiteConfig{
+			{
+				Identifier: "site-1",
+				Deployment: &config.Deployment{
+					Type: config.DeploymentSiteComponent,
+				},
+				Components: []config.SiteComponentConfig{
+					{
+						Name: "component-1",
+						Deployment: &config.Deployment{
+							Type: config.DeploymentSite,
+						},
+					},
+					{
+						Name: "component-2",
+						Deployment: &config.Deployment{
+							Type: config.DeploymentSiteComponent,
+						},
+					},
+					{
+						Name: "component-3",
+						Deployment: &config.Deployment{
+							Type: config.DeploymentSiteComponent,
+						},
+						DependsOn: []string{"component-2"},
+					},
+					{
+						Name: "component-4",
+						Deployment: &config.Deployment{
+							Type: config.DeploymentSiteComponent,
+						},
+						DependsOn: []string{"component-3"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func selectedPaths(v Vertices) []string {
+	var paths []string
+	for _, n := range v {
+		paths = append(paths, n.Path())
+	}
+	return paths
+}
+
+func TestSelectComponents(t *testing.T) {
+	g, err := ToDeploymentGraph(selectTestConfig(), "")
+	assert.NoError(t, err)
+
+	v, err := SelectComponents(g, []string{"component-3"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main/site-1/component-3"}, selectedPaths(v))
+}
+
+func TestSelectComponentsWithDependencies(t *testing.T) {
+	g, err := ToDeploymentGraph(selectTestConfig(), "")
+	assert.NoError(t, err)
+
+	v, err := SelectComponents(g, []string{"component-3"}, &SelectOptions{WithDependencies: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main/site-1", "main/site-1/component-2", "main/site-1/component-3"}, selectedPaths(v))
+}
+
+func TestSelectComponentsWithDependents(t *testing.T) {
+	g, err := ToDeploymentGraph(selectTestConfig(), "")
+	assert.NoError(t, err)
+
+	v, err := SelectComponents(g, []string{"component-2"}, &SelectOptions{WithDependents: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main/site-1/component-2", "main/site-1/component-3", "main/site-1/component-4"},
+		selectedPaths(v))
+}
+
+func TestSelectComponentsNested(t *testing.T) {
+	g, err := ToDeploymentGraph(selectTestConfig(), "")
+	assert.NoError(t, err)
+
+	v, err := SelectComponents(g, []string{"component-1"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main/site-1"}, selectedPaths(v))
+}
+
+func TestSelectComponentsNotFound(t *testing.T) {
+	g, err := ToDeploymentGraph(selectTestConfig(), "")
+	assert.NoError(t, err)
+
+	_, err = SelectComponents(g, []string{"component-5"}, nil)
+	assert.Error(t, err)
+}
@@ -25,12 +25,32 @@ func (s *Site) Hash() (string, error) {
 
 	var hashes []string
 	for _, component := range s.NestedNodes {
-		h, err := HashSiteComponentConfig(component.SiteComponentConfig)
+		h, err := HashNestedComponent(component)
 		if err != nil {
 			return "", err
 		}
 		hashes = append(hashes, h)
 	}
 
+	generated, err := s.GeneratedHash()
+	if err != nil {
+		return "", err
+	}
+	if generated != "" {
+		hashes = append(hashes, generated)
+	}
+
 	return utils.ComputeHash(hashes)
 }
+
+// HashNestedComponent returns the hash of a component that is deployed as part of a site. Only the component config is
+// included, as the terraform file generated for the site covers the rest.
+func HashNestedComponent(sc *SiteComponent) (string, error) {
+	return HashSiteComponentConfig(sc.SiteComponentConfig)
+}
+
+// GeneratedHash returns the hash of the terraform file generated for the site, or an empty string if it is not
+// generated yet. It is stored separately from the hashes of the nested components.
+func (s *Site) GeneratedHash() (string, error) {
+	return hashGeneratedConfig(s)
+}
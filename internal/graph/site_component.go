@@ -3,13 +3,20 @@ package graph
 import (
 	"github.com/dominikbraun/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/config/variable"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"slices"
 	"sort"
+	"strings"
 )
 
 type SiteComponent struct {
 	baseNode
 	SiteConfig          config.SiteConfig
 	SiteComponentConfig config.SiteComponentConfig
+	// outputsDigest is the digest of the upstream outputs referenced by the component. It is empty if the component
+	// does not reference any outputs
+	outputsDigest string
 }
 
 func NewSiteComponent(g graph.Graph[string, Node], path, identifier string, deploymentType config.DeploymentType,
@@ -21,7 +28,43 @@ func NewSiteComponent(g graph.Graph[string, Node], path, identifier string, depl
 }
 
 func (sc *SiteComponent) Hash() (string, error) {
-	return HashSiteComponentConfig(sc.SiteComponentConfig)
+	h, err := HashSiteComponentConfig(sc.SiteComponentConfig)
+	if err != nil {
+		return "", err
+	}
+
+	generated, err := hashGeneratedConfig(sc)
+	if err != nil {
+		return "", err
+	}
+
+	// Only combine the hashes when needed, so the hash of a component that has no generated files and does not
+	// reference outputs equals its config hash
+	if generated == "" && sc.outputsDigest == "" {
+		return h, nil
+	}
+
+	return utils.ComputeHash([]string{h, generated, sc.outputsDigest})
+}
+
+// ReferencedOutputs returns the upstream component outputs referenced in the variables and secrets of the component
+func (sc *SiteComponent) ReferencedOutputs() []variable.OutputReference {
+	references := append(sc.SiteComponentConfig.Variables.ListReferencedOutputs(),
+		sc.SiteComponentConfig.Secrets.ListReferencedOutputs()...)
+	slices.SortFunc(references, func(a, b variable.OutputReference) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return slices.Compact(references)
+}
+
+// OutputsDigest returns the digest of the referenced upstream outputs as set with SetOutputsDigest
+func (sc *SiteComponent) OutputsDigest() string {
+	return sc.outputsDigest
+}
+
+// SetOutputsDigest sets the digest of the referenced upstream outputs, which is included in the hash of the component
+func (sc *SiteComponent) SetOutputsDigest(digest string) {
+	sc.outputsDigest = digest
 }
 
 func SortSiteComponentNodes(nodes []*SiteComponent) {
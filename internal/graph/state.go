@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ComponentState describes where the terraform state of a component is kept
+type ComponentState struct {
+	// Node is the node that holds the state of the component
+	Node Node
+	// ModulePrefix is the prefix of the addresses of the component resources, for example `module.payment.` if the
+	// component is deployed as part of its site. It is empty if the component has its own state.
+	ModulePrefix string
+}
+
+// Address converts an address within the component to the address in the state
+func (s *ComponentState) Address(addr string) string {
+	if s.ModulePrefix == "" || strings.HasPrefix(addr, s.ModulePrefix) {
+		return addr
+	}
+	return s.ModulePrefix + addr
+}
+
+// ResolveComponentState finds the node that holds the state of the component in the given site. Components that are
+// deployed separately have their own state, while other components are a module within the state of the site.
+func ResolveComponentState(g *Graph, site, component string) (*ComponentState, error) {
+	var siteNode *Site
+	for _, n := range g.Vertices() {
+		if s, ok := n.(*Site); ok && s.Identifier() == site {
+			siteNode = s
+			break
+		}
+	}
+	if siteNode == nil {
+		return nil, fmt.Errorf("site %s not found", site)
+	}
+
+	if n, err := g.Vertex(path.Join(siteNode.Path(), component)); err == nil && n.Type() == SiteComponentType {
+		return &ComponentState{Node: n}, nil
+	}
+
+	for _, nested := range siteNode.NestedNodes {
+		if nested.Identifier() == component {
+			return &ComponentState{Node: siteNode, ModulePrefix: fmt.Sprintf("module.%s.", component)}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("component %s not found in site %s", component, site)
+}
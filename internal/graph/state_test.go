@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveComponentStateSeparate(t *testing.T) {
+	g, err := ToDeploymentGraph(selectTestConfig(), "")
+	require.NoError(t, err)
+
+	s, err := ResolveComponentState(g, "site-1", "component-2")
+	require.NoError(t, err)
+	assert.Equal(t, "main/site-1/component-2", s.Node.Path())
+	assert.Equal(t, "aws_s3_bucket.main", s.Address("aws_s3_bucket.main"))
+}
+
+func TestResolveComponentStateNested(t *testing.T) {
+	g, err := ToDeploymentGraph(selectTestConfig(), "")
+	require.NoError(t, err)
+
+	s, err := ResolveComponentState(g, "site-1", "component-1")
+	require.NoError(t, err)
+	assert.Equal(t, "main/site-1", s.Node.Path())
+	assert.Equal(t, "module.component-1.aws_s3_bucket.main", s.Address("aws_s3_bucket.main"))
+	assert.Equal(t, "module.component-1.aws_s3_bucket.main", s.Address("module.component-1.aws_s3_bucket.main"))
+}
+
+func TestResolveComponentStateNotFound(t *testing.T) {
+	g, err := ToDeploymentGraph(selectTestConfig(), "")
+	require.NoError(t, err)
+
+	_, err = ResolveComponentState(g, "site-2", "component-1")
+	assert.ErrorContains(t, err, "site site-2 not found")
+
+	_, err = ResolveComponentState(g, "site-1", "component-5")
+	assert.ErrorContains(t, err, "component component-5 not found in site site-1")
+}
@@ -0,0 +1,197 @@
+package hash
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mach-composer/mach-composer-cli/internal/state"
+)
+
+const azureStorageVersion = "2021-08-06"
+
+// errNoAzureCredentials is returned if neither a SAS token nor an access key is available. Azure AD credentials, as
+// used by the az CLI, managed identities and OIDC, are not supported for the hash storage.
+var errNoAzureCredentials = errors.New("azure hash storage requires either ARM_SAS_TOKEN or ARM_ACCESS_KEY to be set")
+
+// azureStore stores objects in the blob container of the terraform state. Like the azurerm backend, it authenticates
+// with either the ARM_SAS_TOKEN or the ARM_ACCESS_KEY environment variable. The blob ETag is used as version.
+type azureStore struct {
+	client    *http.Client
+	endpoint  string
+	account   string
+	container string
+	sasToken  string
+	accessKey []byte
+}
+
+func newAzureStore(s *state.AzureState) (*azureStore, error) {
+	store := &azureStore{
+		client:    http.DefaultClient,
+		endpoint:  fmt.Sprintf("https://%s.blob.core.windows.net", s.StorageAccount),
+		account:   s.StorageAccount,
+		container: s.ContainerName,
+		sasToken:  strings.TrimPrefix(os.Getenv("ARM_SAS_TOKEN"), "?"),
+	}
+
+	if store.sasToken == "" {
+		key := os.Getenv("ARM_ACCESS_KEY")
+		if key == "" {
+			return nil, errNoAzureCredentials
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ARM_ACCESS_KEY: %w", err)
+		}
+		store.accessKey = decoded
+	}
+
+	return store, nil
+}
+
+func (s *azureStore) newRequest(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	u := fmt.Sprintf("%s/%s/%s", s.endpoint, s.container, (&url.URL{Path: key}).EscapedPath())
+	if s.sasToken != "" {
+		u += "?" + s.sasToken
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureStorageVersion)
+
+	return req, nil
+}
+
+func (s *azureStore) do(req *http.Request) (*http.Response, error) {
+	if s.sasToken == "" {
+		req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", s.account, s.sign(req)))
+	}
+	return s.client.Do(req)
+}
+
+// sign computes the shared key signature of the request as described in
+// https://learn.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func (s *azureStore) sign(req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var msHeaders []string
+	for k := range req.Header {
+		if k := strings.ToLower(k); strings.HasPrefix(k, "x-ms-") {
+			msHeaders = append(msHeaders, k)
+		}
+	}
+	sort.Strings(msHeaders)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range msHeaders {
+		canonicalHeaders.WriteString(fmt.Sprintf("%s:%s\n", k, req.Header.Get(k)))
+	}
+
+	var canonicalResource strings.Builder
+	canonicalResource.WriteString(fmt.Sprintf("/%s%s", s.account, req.URL.EscapedPath()))
+	query := req.URL.Query()
+	var params []string
+	for k := range query {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		canonicalResource.WriteString(fmt.Sprintf("\n%s:%s", strings.ToLower(k), strings.Join(query[k], ",")))
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date is empty because x-ms-date is set
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		canonicalHeaders.String() + canonicalResource.String(),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, s.accessKey)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *azureStore) Get(ctx context.Context, key string) ([]byte, string, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", errObjectNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s while reading blob %s/%s", resp.Status, s.container, key)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, resp.Header.Get("ETag"), nil
+}
+
+func (s *azureStore) Put(ctx context.Context, key string, data []byte, version string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	if version == "" {
+		req.Header.Set("If-None-Match", "*")
+	} else {
+		req.Header.Set("If-Match", version)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return errVersionConflict
+	default:
+		return fmt.Errorf("unexpected status %s while writing blob %s/%s", resp.Status, s.container, key)
+	}
+}
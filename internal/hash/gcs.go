@@ -0,0 +1,103 @@
+package hash
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/oauth2/google"
+
+	"github.com/mach-composer/mach-composer-cli/internal/state"
+)
+
+const (
+	gcsEndpoint = "https://storage.googleapis.com"
+	gcsScope    = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+// gcsStore stores objects in the GCS bucket of the terraform state using the JSON API. The object generation is used
+// as version.
+type gcsStore struct {
+	client   *http.Client
+	endpoint string
+	bucket   string
+}
+
+func newGCSStore(ctx context.Context, s *state.GcpState) (*gcsStore, error) {
+	client, err := google.DefaultClient(ctx, gcsScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcs client: %w", err)
+	}
+
+	return &gcsStore{
+		client:   client,
+		endpoint: gcsEndpoint,
+		bucket:   s.Bucket,
+	}, nil
+}
+
+func (s *gcsStore) Get(ctx context.Context, key string) ([]byte, string, error) {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", s.endpoint, url.PathEscape(s.bucket), url.PathEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", errObjectNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s while reading gs://%s/%s", resp.Status, s.bucket, key)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, resp.Header.Get("X-Goog-Generation"), nil
+}
+
+func (s *gcsStore) Put(ctx context.Context, key string, data []byte, version string) error {
+	// A generation of 0 means the object must not exist yet
+	generation := version
+	if generation == "" {
+		generation = "0"
+	}
+
+	q := url.Values{}
+	q.Set("uploadType", "media")
+	q.Set("name", key)
+	q.Set("ifGenerationMatch", generation)
+
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", s.endpoint, url.PathEscape(s.bucket), q.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed:
+		return errVersionConflict
+	default:
+		return fmt.Errorf("unexpected status %s while writing gs://%s/%s", resp.Status, s.bucket, key)
+	}
+}
@@ -2,23 +2,133 @@ package hash
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/creasty/defaults"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rs/zerolog/log"
+
 	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
-	"os"
+	"github.com/mach-composer/mach-composer-cli/internal/state"
 )
 
 const defaultHashFile = ".mach-composer/hashes.json"
 
 type Handler interface {
 	Store(ctx context.Context, n graph.Node) error
+	// Delete removes the stored hash of the node, for example after it is destroyed
+	Delete(ctx context.Context, n graph.Node) error
 	Fetch(ctx context.Context, n graph.Node) (string, error)
+	// FetchOutputsDigest returns the digest of the referenced upstream outputs stored together with the hash of a
+	// site component, or an empty string if none was stored
+	FetchOutputsDigest(ctx context.Context, n graph.Node) (string, error)
+	// FetchLayout returns the deployment type of every component at the time it was last stored
+	FetchLayout(ctx context.Context) (Layout, error)
+	// StoreLayout records the deployment type of a component, for example after its state was migrated
+	StoreLayout(ctx context.Context, key string, t config.DeploymentType) error
+}
+
+// Factory returns the hash handler for the given config. The hashes are stored next to the terraform state as
+// configured in `global.terraform_config.remote_state`. The MC_HASH_FILE environment variable can be used to
+// always store the hashes in a local file instead.
+func Factory(ctx context.Context, cfg *config.MachConfig) (Handler, error) {
+	if hashFile := os.Getenv("MC_HASH_FILE"); hashFile != "" {
+		return NewJsonFileHandler(hashFile), nil
+	}
+
+	var data map[string]any
+	if cfg.Global.TerraformConfig != nil {
+		data = cfg.Global.TerraformConfig.RemoteState
+	}
+
+	key := objectKey(cfg)
+
+	switch typ := state.Type(cfg.Global.TerraformStateProvider); typ {
+	case state.DefaultType, state.LocalType:
+		s := &state.LocalState{}
+		if err := decodeState(data, s); err != nil {
+			return nil, err
+		}
+		if s.Path == "" {
+			return NewJsonFileHandler(defaultHashFile), nil
+		}
+
+		hashFile := path.Join(s.Path, defaultHashFile)
+		if err := migrateHashFile(defaultHashFile, hashFile); err != nil {
+			return nil, err
+		}
+		return NewJsonFileHandler(hashFile), nil
+	case state.AwsType:
+		s := &state.AwsState{}
+		if err := decodeState(data, s); err != nil {
+			return nil, err
+		}
+		store, err := newS3Store(s)
+		if err != nil {
+			return nil, err
+		}
+		return NewRemoteHandler(store, path.Join(s.KeyPrefix, key)), nil
+	case state.GcpType:
+		s := &state.GcpState{}
+		if err := decodeState(data, s); err != nil {
+			return nil, err
+		}
+		store, err := newGCSStore(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		return NewRemoteHandler(store, s.Key(key)), nil
+	case state.AzureType:
+		s := &state.AzureState{}
+		if err := decodeState(data, s); err != nil {
+			return nil, err
+		}
+		store, err := newAzureStore(s)
+		if errors.Is(err, errNoAzureCredentials) {
+			log.Warn().Msgf("Remote hash storage requires ARM_SAS_TOKEN or ARM_ACCESS_KEY to be set for the azurerm "+
+				"backend; using local file %s", defaultHashFile)
+			return NewJsonFileHandler(defaultHashFile), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return NewRemoteHandler(store, s.Key(key)), nil
+	default:
+		log.Warn().Msgf("Remote hash storage is not supported for state type %s; using local file %s", typ,
+			defaultHashFile)
+		return NewJsonFileHandler(defaultHashFile), nil
+	}
 }
 
-func Factory(_ *config.MachConfig) Handler {
-	hashFile := os.Getenv("MC_HASH_FILE")
-	if hashFile == "" {
-		hashFile = defaultHashFile
+// migrateHashFile copies the hashes from the legacy location in the working directory to the hash file under the
+// state path, unless that already exists. Otherwise every node would be considered never deployed after upgrading.
+func migrateHashFile(legacy, hashFile string) error {
+	if _, err := os.Stat(hashFile); err == nil || !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	data, err := os.ReadFile(legacy)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read hashes from %s: %w", legacy, err)
 	}
 
-	return NewJsonFileHandler(hashFile)
+	log.Info().Msgf("Copying hashes from %s to %s", legacy, hashFile)
+	if err = os.MkdirAll(path.Dir(hashFile), 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(hashFile, data, 0777)
+}
+
+func decodeState(data map[string]any, s any) error {
+	if err := mapstructure.Decode(data, s); err != nil {
+		return fmt.Errorf("failed to decode remote state config: %w", err)
+	}
+	return defaults.Set(s)
 }
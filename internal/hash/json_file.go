@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/utils"
 	"github.com/rs/zerolog/log"
@@ -14,8 +15,89 @@ import (
 
 var mutex = &sync.RWMutex{}
 
+// FormatVersion is the current version of the hash file format
+const FormatVersion = 2
+
 type Hashes map[string]string
 
+// Layout contains the deployment type of every component, keyed like the component hashes
+type Layout map[string]config.DeploymentType
+
+// Document is the content of the hash file
+type Document struct {
+	Version int    `json:"version"`
+	Hashes  Hashes `json:"hashes"`
+	// Legacy contains the entries of the unversioned format, which were keyed by component name only. They are used
+	// as a fallback for nodes that have not been stored since the migration
+	Legacy Hashes `json:"legacy,omitempty"`
+	// Outputs contains the digests of the upstream outputs referenced by a site component at the time it was stored.
+	// They allow comparing hashes without reading the outputs from the terraform state
+	Outputs Hashes `json:"outputs,omitempty"`
+	// Layout contains the deployment type of every component at the time it was stored, so that changes of the
+	// deployment type can be detected
+	Layout Layout `json:"layout,omitempty"`
+}
+
+func (d *Document) setLayout(key string, t config.DeploymentType) {
+	if d.Layout == nil {
+		d.Layout = Layout{}
+	}
+	d.Layout[key] = t
+}
+
+// Get returns the hash stored under the given key, falling back to the legacy entry of the component
+func (d *Document) Get(key, name string) string {
+	if h, ok := d.Hashes[key]; ok {
+		return h
+	}
+	return d.Legacy[name]
+}
+
+// parseDocument parses the content of a hash file. Files in the unversioned format (a flat map of component names to
+// hashes) are migrated to the current format.
+func parseDocument(c []byte) (*Document, error) {
+	doc := &Document{Version: FormatVersion, Hashes: Hashes{}}
+	if len(c) == 0 {
+		return doc, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(c, &raw); err != nil {
+		return nil, err
+	}
+
+	if _, ok := raw["version"]; !ok {
+		log.Debug().Msgf("Migrating hashes from the unversioned format to version %d", FormatVersion)
+		if err := json.Unmarshal(c, &doc.Legacy); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+
+	if err := json.Unmarshal(c, doc); err != nil {
+		return nil, err
+	}
+	if doc.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported hash file version %d (latest supported version is %d)", doc.Version,
+			FormatVersion)
+	}
+	if doc.Hashes == nil {
+		doc.Hashes = Hashes{}
+	}
+	doc.Version = FormatVersion
+
+	return doc, nil
+}
+
+// Key returns the key under which the hash of a node is stored. Site components are keyed by their site and name,
+// as the same component can be part of multiple sites.
+func Key(n graph.Node) string {
+	if sc, ok := n.(*graph.SiteComponent); ok {
+		return path.Join(sc.SiteConfig.Identifier, sc.Identifier())
+	}
+	return n.Identifier()
+}
+
 type JsonFileHandler struct {
 	file string
 }
@@ -33,9 +115,7 @@ func NewJsonFileHandler(file string) Handler {
 	}
 }
 
-func (h *JsonFileHandler) getHashes() (*Hashes, error) {
-	var hashes Hashes
-
+func (h *JsonFileHandler) getDocument() (*Document, error) {
 	f, err := os.OpenFile(h.file, os.O_RDONLY|os.O_CREATE, 0777)
 	if err != nil {
 		return nil, err
@@ -47,27 +127,88 @@ func (h *JsonFileHandler) getHashes() (*Hashes, error) {
 		return nil, err
 	}
 
-	if len(c) == 0 {
-		return &Hashes{}, nil
-	}
+	return parseDocument(c)
+}
+
+func (h *JsonFileHandler) Fetch(_ context.Context, n graph.Node) (string, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
 
-	err = json.Unmarshal(c, &hashes)
+	doc, err := h.getDocument()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	return &hashes, nil
+	return fetchFromDocument(doc, n)
 }
 
-func (h *JsonFileHandler) Fetch(_ context.Context, n graph.Node) (string, error) {
+func (h *JsonFileHandler) FetchOutputsDigest(_ context.Context, n graph.Node) (string, error) {
 	mutex.RLock()
 	defer mutex.RUnlock()
 
-	hashes, err := h.getHashes()
+	doc, err := h.getDocument()
 	if err != nil {
 		return "", err
 	}
 
+	return doc.Outputs[Key(n)], nil
+}
+
+func (h *JsonFileHandler) FetchLayout(_ context.Context) (Layout, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	doc, err := h.getDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.Layout, nil
+}
+
+func (h *JsonFileHandler) StoreLayout(_ context.Context, key string, t config.DeploymentType) error {
+	return h.update(func(doc *Document) error {
+		doc.setLayout(key, t)
+		return nil
+	})
+}
+
+func (h *JsonFileHandler) Store(_ context.Context, n graph.Node) error {
+	return h.update(func(doc *Document) error {
+		return storeInDocument(doc, n)
+	})
+}
+
+func (h *JsonFileHandler) Delete(_ context.Context, n graph.Node) error {
+	return h.update(func(doc *Document) error {
+		return deleteFromDocument(doc, n)
+	})
+}
+
+func (h *JsonFileHandler) update(modify func(doc *Document) error) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	doc, err := h.getDocument()
+	if err != nil {
+		return err
+	}
+
+	if err = modify(doc); err != nil {
+		return err
+	}
+
+	c, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(h.file, c, 0777)
+}
+
+// fetchFromDocument returns the stored hash of the node. For sites the hash is computed from the stored hashes of the
+// nested components and the generated site config, so it can be compared to the hash of the site node.
+func fetchFromDocument(doc *Document, n graph.Node) (string, error) {
 	switch n.Type() {
 	case graph.ProjectType:
 		return "", nil
@@ -75,52 +216,102 @@ func (h *JsonFileHandler) Fetch(_ context.Context, n graph.Node) (string, error)
 		s := n.(*graph.Site)
 		graph.SortSiteComponentNodes(s.NestedNodes)
 
+		// A site of which nothing was stored has never been deployed
+		found := false
+
 		var componentHashes []string
 		for _, component := range s.NestedNodes {
-			h := (*hashes)[component.Identifier()]
+			h := doc.Get(Key(component), component.Identifier())
+			found = found || h != ""
 			componentHashes = append(componentHashes, h)
 		}
+		if h := doc.Hashes[Key(n)]; h != "" {
+			found = true
+			componentHashes = append(componentHashes, h)
+		}
+
+		if !found {
+			return "", nil
+		}
 
 		return utils.ComputeHash(componentHashes)
 	case graph.SiteComponentType:
-		return (*hashes)[n.Identifier()], nil
+		return doc.Get(Key(n), n.Identifier()), nil
 	default:
 		return "", fmt.Errorf("unknown node type %T", n)
 	}
 }
 
-func (h *JsonFileHandler) Store(_ context.Context, n graph.Node) error {
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	hashes, err := h.getHashes()
-	if err != nil {
-		return err
-	}
+// storeInDocument sets the current hash of the node in the document. For sites the hashes of all nested components
+// and the hash of the generated site config are stored.
+func storeInDocument(doc *Document, n graph.Node) error {
+	var err error
 
 	switch n.Type() {
 	case graph.ProjectType:
 		return nil
 	case graph.SiteType:
-		for _, nn := range n.(*graph.Site).NestedNodes {
-			(*hashes)[nn.Identifier()], err = nn.Hash()
+		s := n.(*graph.Site)
+		for _, nn := range s.NestedNodes {
+			doc.Hashes[Key(nn)], err = graph.HashNestedComponent(nn)
 			if err != nil {
 				return err
 			}
+			doc.setLayout(Key(nn), config.DeploymentSite)
+		}
+
+		generated, err := s.GeneratedHash()
+		if err != nil {
+			return err
+		}
+		if generated == "" {
+			delete(doc.Hashes, Key(n))
+		} else {
+			doc.Hashes[Key(n)] = generated
 		}
 	case graph.SiteComponentType:
-		(*hashes)[n.Identifier()], err = n.Hash()
+		doc.Hashes[Key(n)], err = n.Hash()
 		if err != nil {
 			return err
 		}
+		doc.setLayout(Key(n), config.DeploymentSiteComponent)
+
+		if sc, ok := n.(*graph.SiteComponent); ok && sc.OutputsDigest() != "" {
+			if doc.Outputs == nil {
+				doc.Outputs = Hashes{}
+			}
+			doc.Outputs[Key(n)] = sc.OutputsDigest()
+		} else {
+			delete(doc.Outputs, Key(n))
+		}
 	default:
 		return fmt.Errorf("unknown node type %T", n)
 	}
 
-	c, err := json.Marshal(hashes)
-	if err != nil {
-		return err
+	return nil
+}
+
+// deleteFromDocument removes the stored hash of the node, so it is considered never deployed. For site components
+// an empty hash is stored instead of removing the entry, as otherwise the legacy entry of the component would be
+// used as fallback.
+func deleteFromDocument(doc *Document, n graph.Node) error {
+	switch n.Type() {
+	case graph.ProjectType:
+		return nil
+	case graph.SiteType:
+		for _, nn := range n.(*graph.Site).NestedNodes {
+			doc.Hashes[Key(nn)] = ""
+			delete(doc.Outputs, Key(nn))
+			delete(doc.Layout, Key(nn))
+		}
+		delete(doc.Hashes, Key(n))
+	case graph.SiteComponentType:
+		doc.Hashes[Key(n)] = ""
+		delete(doc.Outputs, Key(n))
+		delete(doc.Layout, Key(n))
+	default:
+		return fmt.Errorf("unknown node type %T", n)
 	}
 
-	return os.WriteFile(h.file, c, 0777)
+	return nil
 }
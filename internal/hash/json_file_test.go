@@ -0,0 +1,242 @@
+package hash
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"testing"
+)
+
+func newSiteComponent(site, name string) *graph.SiteComponent {
+	return graph.NewSiteComponent(nil, path.Join("main", site, name), name, config.DeploymentSiteComponent, nil,
+		config.SiteConfig{Identifier: site},
+		config.SiteComponentConfig{Name: name, Definition: &config.ComponentConfig{Name: name, Version: site}},
+	)
+}
+
+func TestJsonFileHandlerSameComponentInMultipleSites(t *testing.T) {
+	file := path.Join(t.TempDir(), "hashes.json")
+	h := NewJsonFileHandler(file)
+
+	nl := newSiteComponent("nl", "payment")
+	de := newSiteComponent("de", "payment")
+
+	assert.NoError(t, h.Store(context.Background(), nl))
+	assert.NoError(t, h.Store(context.Background(), de))
+
+	nlHash, _ := nl.Hash()
+	deHash, _ := de.Hash()
+	assert.NotEqual(t, nlHash, deHash)
+
+	v, err := h.Fetch(context.Background(), nl)
+	assert.NoError(t, err)
+	assert.Equal(t, nlHash, v)
+
+	v, err = h.Fetch(context.Background(), de)
+	assert.NoError(t, err)
+	assert.Equal(t, deHash, v)
+
+	c, _ := os.ReadFile(file)
+	var doc Document
+	assert.NoError(t, json.Unmarshal(c, &doc))
+	assert.Equal(t, FormatVersion, doc.Version)
+	assert.Equal(t, Hashes{"nl/payment": nlHash, "de/payment": deHash}, doc.Hashes)
+}
+
+func TestJsonFileHandlerMigrateUnversioned(t *testing.T) {
+	file := path.Join(t.TempDir(), "hashes.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{"payment":"old-hash"}`), 0777))
+	h := NewJsonFileHandler(file)
+
+	nl := newSiteComponent("nl", "payment")
+	de := newSiteComponent("de", "payment")
+
+	v, err := h.Fetch(context.Background(), nl)
+	assert.NoError(t, err)
+	assert.Equal(t, "old-hash", v)
+
+	assert.NoError(t, h.Store(context.Background(), nl))
+
+	nlHash, _ := nl.Hash()
+	v, err = h.Fetch(context.Background(), nl)
+	assert.NoError(t, err)
+	assert.Equal(t, nlHash, v)
+
+	v, err = h.Fetch(context.Background(), de)
+	assert.NoError(t, err)
+	assert.Equal(t, "old-hash", v)
+
+	c, _ := os.ReadFile(file)
+	var doc Document
+	assert.NoError(t, json.Unmarshal(c, &doc))
+	assert.Equal(t, FormatVersion, doc.Version)
+	assert.Equal(t, Hashes{"payment": "old-hash"}, doc.Legacy)
+}
+
+func TestParseDocumentUnsupportedVersion(t *testing.T) {
+	_, err := parseDocument([]byte(`{"version": 99, "hashes": {}}`))
+	assert.Error(t, err)
+}
+
+func TestJsonFileHandlerSite(t *testing.T) {
+	h := NewJsonFileHandler(path.Join(t.TempDir(), "hashes.json"))
+
+	site := graph.NewSite(nil, path.Join(t.TempDir(), "nl"), "nl", config.DeploymentSite, nil,
+		config.SiteConfig{Identifier: "nl"})
+	site.NestedNodes = []*graph.SiteComponent{newSiteComponent("nl", "payment"), newSiteComponent("nl", "api")}
+	assert.NoError(t, os.MkdirAll(site.Path(), 0700))
+	assert.NoError(t, os.WriteFile(path.Join(site.Path(), "main.tf"), []byte(`provider "aws" {}`), 0600))
+
+	assert.NoError(t, h.Store(context.Background(), site))
+
+	siteHash, err := site.Hash()
+	assert.NoError(t, err)
+
+	v, err := h.Fetch(context.Background(), site)
+	assert.NoError(t, err)
+	assert.Equal(t, siteHash, v)
+
+	// A change in the generated site config is detected
+	assert.NoError(t, os.WriteFile(path.Join(site.Path(), "main.tf"), []byte(`provider "aws" { region = "eu-west-1" }`), 0600))
+	siteHash, err = site.Hash()
+	assert.NoError(t, err)
+	assert.NotEqual(t, siteHash, v)
+}
+
+func TestJsonFileHandlerSiteStaleComponentConfig(t *testing.T) {
+	h := NewJsonFileHandler(path.Join(t.TempDir(), "hashes.json"))
+
+	site := graph.NewSite(nil, path.Join(t.TempDir(), "nl"), "nl", config.DeploymentSite, nil,
+		config.SiteConfig{Identifier: "nl"})
+	payment := graph.NewSiteComponent(nil, path.Join(site.Path(), "payment"), "payment", config.DeploymentSite, site,
+		config.SiteConfig{Identifier: "nl"},
+		config.SiteComponentConfig{Name: "payment", Definition: &config.ComponentConfig{Name: "payment"}},
+	)
+	payment.SetOutputsDigest("digest")
+	site.NestedNodes = []*graph.SiteComponent{payment}
+
+	// The file generated for the component when it was deployed on its own is not part of the site
+	assert.NoError(t, os.MkdirAll(payment.Path(), 0700))
+	assert.NoError(t, os.WriteFile(path.Join(payment.Path(), "main.tf"), []byte(`provider "aws" {}`), 0600))
+
+	assert.NoError(t, h.Store(context.Background(), site))
+
+	siteHash, err := site.Hash()
+	assert.NoError(t, err)
+
+	v, err := h.Fetch(context.Background(), site)
+	assert.NoError(t, err)
+	assert.Equal(t, siteHash, v)
+}
+
+func TestJsonFileHandlerOutputsDigest(t *testing.T) {
+	h := NewJsonFileHandler(path.Join(t.TempDir(), "hashes.json"))
+
+	sc := newSiteComponent("nl", "payment")
+	sc.SetOutputsDigest("digest")
+	assert.NoError(t, h.Store(context.Background(), sc))
+
+	v, err := h.FetchOutputsDigest(context.Background(), sc)
+	assert.NoError(t, err)
+	assert.Equal(t, "digest", v)
+
+	// The digest is removed once the component no longer references outputs
+	sc.SetOutputsDigest("")
+	assert.NoError(t, h.Store(context.Background(), sc))
+
+	v, err = h.FetchOutputsDigest(context.Background(), sc)
+	assert.NoError(t, err)
+	assert.Equal(t, "", v)
+}
+
+func TestJsonFileHandlerSiteNeverDeployed(t *testing.T) {
+	h := NewJsonFileHandler(path.Join(t.TempDir(), "hashes.json"))
+
+	site := graph.NewSite(nil, path.Join(t.TempDir(), "nl"), "nl", config.DeploymentSite, nil,
+		config.SiteConfig{Identifier: "nl"})
+	site.NestedNodes = []*graph.SiteComponent{newSiteComponent("nl", "payment")}
+
+	v, err := h.Fetch(context.Background(), site)
+	assert.NoError(t, err)
+	assert.Equal(t, "", v)
+}
+
+func TestJsonFileHandlerDelete(t *testing.T) {
+	file := path.Join(t.TempDir(), "hashes.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{"payment": "legacy"}`), 0600))
+	h := NewJsonFileHandler(file)
+
+	nl := newSiteComponent("nl", "payment")
+	de := newSiteComponent("de", "payment")
+	nl.SetOutputsDigest("digest")
+	assert.NoError(t, h.Store(context.Background(), nl))
+
+	assert.NoError(t, h.Delete(context.Background(), nl))
+
+	v, err := h.Fetch(context.Background(), nl)
+	assert.NoError(t, err)
+	assert.Equal(t, "", v, "a deleted component should not fall back to the legacy entry")
+
+	v, err = h.FetchOutputsDigest(context.Background(), nl)
+	assert.NoError(t, err)
+	assert.Equal(t, "", v)
+
+	// Other sites still use the legacy entry
+	v, err = h.Fetch(context.Background(), de)
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy", v)
+}
+
+func TestJsonFileHandlerLayout(t *testing.T) {
+	h := NewJsonFileHandler(path.Join(t.TempDir(), "hashes.json"))
+
+	site := graph.NewSite(nil, path.Join(t.TempDir(), "nl"), "nl", config.DeploymentSite, nil,
+		config.SiteConfig{Identifier: "nl"})
+	site.NestedNodes = []*graph.SiteComponent{newSiteComponent("nl", "api")}
+	payment := newSiteComponent("nl", "payment")
+
+	assert.NoError(t, h.Store(context.Background(), site))
+	assert.NoError(t, h.Store(context.Background(), payment))
+
+	layout, err := h.FetchLayout(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Layout{"nl/api": config.DeploymentSite, "nl/payment": config.DeploymentSiteComponent}, layout)
+
+	assert.NoError(t, h.StoreLayout(context.Background(), "nl/api", config.DeploymentSiteComponent))
+	assert.NoError(t, h.Delete(context.Background(), payment))
+
+	layout, err = h.FetchLayout(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Layout{"nl/api": config.DeploymentSiteComponent}, layout)
+}
+
+func TestMigrateHashFile(t *testing.T) {
+	dir := t.TempDir()
+	legacy := path.Join(dir, ".mach-composer", "hashes.json")
+	hashFile := path.Join(dir, "states", ".mach-composer", "hashes.json")
+
+	// Nothing is migrated if there are no legacy hashes
+	assert.NoError(t, migrateHashFile(legacy, hashFile))
+	assert.NoFileExists(t, hashFile)
+
+	nl := newSiteComponent("nl", "payment")
+	assert.NoError(t, NewJsonFileHandler(legacy).Store(context.Background(), nl))
+
+	// The legacy hashes are used once the hash file is created under the state path
+	assert.NoError(t, migrateHashFile(legacy, hashFile))
+	nlHash, _ := nl.Hash()
+	v, err := NewJsonFileHandler(hashFile).Fetch(context.Background(), nl)
+	assert.NoError(t, err)
+	assert.Equal(t, nlHash, v)
+
+	// An existing hash file is not overwritten
+	assert.NoError(t, NewJsonFileHandler(hashFile).Delete(context.Background(), nl))
+	assert.NoError(t, migrateHashFile(legacy, hashFile))
+	v, err = NewJsonFileHandler(hashFile).Fetch(context.Background(), nl)
+	assert.NoError(t, err)
+	assert.Empty(t, v)
+}
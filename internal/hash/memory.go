@@ -2,6 +2,7 @@ package hash
 
 import (
 	"context"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 )
 
@@ -12,6 +13,7 @@ type Entry struct {
 
 type MemoryMap struct {
 	InternalMap map[string]string
+	Layout      Layout
 }
 
 func NewMemoryMapHandler(entries ...Entry) Handler {
@@ -29,9 +31,30 @@ func NewMemoryMapHandler(entries ...Entry) Handler {
 func (h *MemoryMap) Fetch(_ context.Context, n graph.Node) (string, error) {
 	return h.InternalMap[n.Identifier()], nil
 }
+func (h *MemoryMap) FetchOutputsDigest(_ context.Context, _ graph.Node) (string, error) {
+	return "", nil
+}
+
+func (h *MemoryMap) FetchLayout(_ context.Context) (Layout, error) {
+	return h.Layout, nil
+}
+
+func (h *MemoryMap) StoreLayout(_ context.Context, key string, t config.DeploymentType) error {
+	if h.Layout == nil {
+		h.Layout = Layout{}
+	}
+	h.Layout[key] = t
+	return nil
+}
+
 func (h *MemoryMap) Store(_ context.Context, n graph.Node) error {
 	var err error
 	h.InternalMap[n.Identifier()], err = n.Hash()
 
 	return err
 }
+
+func (h *MemoryMap) Delete(_ context.Context, n graph.Node) error {
+	delete(h.InternalMap, n.Identifier())
+	return nil
+}
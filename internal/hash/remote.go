@@ -0,0 +1,179 @@
+package hash
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/rs/zerolog/log"
+)
+
+const maxStoreAttempts = 5
+
+var (
+	errObjectNotFound  = errors.New("object not found")
+	errVersionConflict = errors.New("object was modified concurrently")
+)
+
+// objectStore is a minimal object storage abstraction that supports conditional writes
+type objectStore interface {
+	// Get returns the content and version of the object. If the object does not exist errObjectNotFound is returned
+	Get(ctx context.Context, key string) ([]byte, string, error)
+	// Put writes the object if its current version matches the given version. An empty version means the object
+	// must not exist yet. If the version does not match errVersionConflict is returned
+	Put(ctx context.Context, key string, data []byte, version string) error
+}
+
+// objectKey returns the name of the hash document in remote storage, relative to the configured state prefix. The name
+// is derived from a digest of the project identifier and environment, so multiple projects can share the same bucket
+// and state prefix.
+func objectKey(cfg *config.MachConfig) string {
+	project := strings.TrimSuffix(filepath.Base(cfg.Filename), filepath.Ext(cfg.Filename))
+	digest := sha256.Sum256([]byte(project + "/" + cfg.Global.Environment))
+	return fmt.Sprintf("mach-composer/hashes-%s.json", hex.EncodeToString(digest[:])[:16])
+}
+
+// RemoteHandler stores the hashes as a single document in an object store. Writes use optimistic concurrency: the
+// document is only written if it was not changed since it was read, otherwise the write is retried with the latest
+// version of the document.
+type RemoteHandler struct {
+	store objectStore
+	key   string
+
+	mu  sync.Mutex
+	doc *Document
+}
+
+func NewRemoteHandler(store objectStore, key string) *RemoteHandler {
+	return &RemoteHandler{
+		store: store,
+		key:   key,
+	}
+}
+
+func (h *RemoteHandler) load(ctx context.Context) (*Document, string, error) {
+	data, version, err := h.store.Get(ctx, h.key)
+	if errors.Is(err, errObjectNotFound) {
+		doc, err := parseDocument(nil)
+		return doc, "", err
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read hashes from %s: %w", h.key, err)
+	}
+
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return doc, version, nil
+}
+
+func (h *RemoteHandler) Fetch(ctx context.Context, n graph.Node) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.doc == nil {
+		doc, _, err := h.load(ctx)
+		if err != nil {
+			return "", err
+		}
+		h.doc = doc
+	}
+
+	return fetchFromDocument(h.doc, n)
+}
+
+func (h *RemoteHandler) FetchOutputsDigest(ctx context.Context, n graph.Node) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.doc == nil {
+		doc, _, err := h.load(ctx)
+		if err != nil {
+			return "", err
+		}
+		h.doc = doc
+	}
+
+	return h.doc.Outputs[Key(n)], nil
+}
+
+func (h *RemoteHandler) FetchLayout(ctx context.Context) (Layout, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.doc == nil {
+		doc, _, err := h.load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		h.doc = doc
+	}
+
+	return h.doc.Layout, nil
+}
+
+func (h *RemoteHandler) StoreLayout(ctx context.Context, key string, t config.DeploymentType) error {
+	return h.update(ctx, func(doc *Document) error {
+		doc.setLayout(key, t)
+		return nil
+	})
+}
+
+func (h *RemoteHandler) Store(ctx context.Context, n graph.Node) error {
+	return h.update(ctx, func(doc *Document) error {
+		return storeInDocument(doc, n)
+	})
+}
+
+func (h *RemoteHandler) Delete(ctx context.Context, n graph.Node) error {
+	return h.update(ctx, func(doc *Document) error {
+		return deleteFromDocument(doc, n)
+	})
+}
+
+// update applies the modification to the latest version of the document and writes it, retrying if the document
+// was modified concurrently
+func (h *RemoteHandler) update(ctx context.Context, modify func(doc *Document) error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for attempt := 1; attempt <= maxStoreAttempts; attempt++ {
+		doc, version, err := h.load(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err = modify(doc); err != nil {
+			return err
+		}
+
+		c, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+
+		err = h.store.Put(ctx, h.key, c, version)
+		if errors.Is(err, errVersionConflict) {
+			log.Debug().Msgf("Hashes in %s were modified concurrently, retrying (attempt %d)", h.key, attempt)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write hashes to %s: %w", h.key, err)
+		}
+
+		h.doc = doc
+		return nil
+	}
+
+	return fmt.Errorf("failed to write hashes to %s: %w after %d attempts", h.key, errVersionConflict,
+		maxStoreAttempts)
+}
@@ -0,0 +1,334 @@
+package hash
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/state"
+)
+
+type fakeObject struct {
+	data       []byte
+	generation int
+}
+
+// fakeObjectServer is a minimal in-memory stand-in for the S3, GCS and Azure blob storage APIs, including their
+// conditional write semantics
+type fakeObjectServer struct {
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	// requests contains the received requests, used to verify authentication headers
+	requests []*http.Request
+}
+
+func newFakeObjectServer() *fakeObjectServer {
+	return &fakeObjectServer{objects: map[string]*fakeObject{}}
+}
+
+func (s *fakeObjectServer) get(w http.ResponseWriter, key string, setVersion func(o *fakeObject)) {
+	o, ok := s.objects[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	setVersion(o)
+	_, _ = w.Write(o.data)
+}
+
+// put writes the object if the expected generation matches. An expected generation of 0 means the object must not
+// exist yet, -1 means the write is unconditional
+func (s *fakeObjectServer) put(w http.ResponseWriter, r *http.Request, key string, expected int, status int) {
+	o, ok := s.objects[key]
+	current := 0
+	if ok {
+		current = o.generation
+	}
+	if expected >= 0 && expected != current {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	data, _ := io.ReadAll(r.Body)
+	s.objects[key] = &fakeObject{data: data, generation: current + 1}
+	w.WriteHeader(status)
+}
+
+func etag(o *fakeObject) string {
+	return fmt.Sprintf(`"%d"`, o.generation)
+}
+
+// expectedFromHeaders converts the If-Match/If-None-Match headers to an expected generation
+func expectedFromHeaders(r *http.Request) int {
+	if r.Header.Get("If-None-Match") == "*" {
+		return 0
+	}
+	if v := r.Header.Get("If-Match"); v != "" {
+		g, _ := strconv.Atoi(strings.Trim(v, `"`))
+		return g
+	}
+	return -1
+}
+
+func (s *fakeObjectServer) s3Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r)
+
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		switch r.Method {
+		case http.MethodGet:
+			s.get(w, key, func(o *fakeObject) { w.Header().Set("ETag", etag(o)) })
+		case http.MethodPut:
+			s.put(w, r, key, expectedFromHeaders(r), http.StatusOK)
+		}
+	})
+}
+
+func (s *fakeObjectServer) gcsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r)
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/storage/v1/b/"):
+			parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/storage/v1/b/"), "/o/", 2)
+			s.get(w, parts[0]+"/"+parts[1], func(o *fakeObject) {
+				w.Header().Set("X-Goog-Generation", strconv.Itoa(o.generation))
+			})
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload/storage/v1/b/"):
+			bucket := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/upload/storage/v1/b/"), "/o")
+			expected, _ := strconv.Atoi(r.URL.Query().Get("ifGenerationMatch"))
+			s.put(w, r, bucket+"/"+r.URL.Query().Get("name"), expected, http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+}
+
+func (s *fakeObjectServer) azureHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r)
+
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		switch r.Method {
+		case http.MethodGet:
+			s.get(w, key, func(o *fakeObject) { w.Header().Set("ETag", etag(o)) })
+		case http.MethodPut:
+			if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			s.put(w, r, key, expectedFromHeaders(r), http.StatusCreated)
+		}
+	})
+}
+
+func testRemoteHandler(t *testing.T, store objectStore) {
+	ctx := context.Background()
+	h := NewRemoteHandler(store, "prefix/hashes.json")
+
+	nl := newSiteComponent("nl", "payment")
+	de := newSiteComponent("de", "payment")
+
+	v, err := h.Fetch(ctx, nl)
+	require.NoError(t, err)
+	assert.Equal(t, "", v)
+
+	require.NoError(t, h.Store(ctx, nl))
+	require.NoError(t, h.Store(ctx, de))
+
+	// A second handler, as used by a different CI runner, should see the stored hashes
+	other := NewRemoteHandler(store, "prefix/hashes.json")
+
+	nlHash, _ := nl.Hash()
+	v, err = other.Fetch(ctx, nl)
+	require.NoError(t, err)
+	assert.Equal(t, nlHash, v)
+
+	deHash, _ := de.Hash()
+	v, err = other.Fetch(ctx, de)
+	require.NoError(t, err)
+	assert.Equal(t, deHash, v)
+}
+
+func TestRemoteHandlerS3(t *testing.T) {
+	server := newFakeObjectServer()
+	ts := httptest.NewServer(server.s3Handler())
+	defer ts.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:         aws.String(ts.URL),
+		Region:           aws.String("eu-central-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("key", "secret", ""),
+	}))
+
+	testRemoteHandler(t, &s3Store{client: s3.New(sess), bucket: "bucket"})
+	assert.Contains(t, server.objects, "bucket/prefix/hashes.json")
+}
+
+func TestRemoteHandlerGCS(t *testing.T) {
+	server := newFakeObjectServer()
+	ts := httptest.NewServer(server.gcsHandler())
+	defer ts.Close()
+
+	testRemoteHandler(t, &gcsStore{client: ts.Client(), endpoint: ts.URL, bucket: "bucket"})
+	assert.Contains(t, server.objects, "bucket/prefix/hashes.json")
+}
+
+func TestRemoteHandlerAzureSAS(t *testing.T) {
+	server := newFakeObjectServer()
+	ts := httptest.NewServer(server.azureHandler())
+	defer ts.Close()
+
+	testRemoteHandler(t, &azureStore{
+		client:    ts.Client(),
+		endpoint:  ts.URL,
+		account:   "account",
+		container: "container",
+		sasToken:  "sv=2021-08-06&sig=signature",
+	})
+	assert.Contains(t, server.objects, "container/prefix/hashes.json")
+	for _, r := range server.requests {
+		assert.Equal(t, "signature", r.URL.Query().Get("sig"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+	}
+}
+
+func TestRemoteHandlerAzureSharedKey(t *testing.T) {
+	server := newFakeObjectServer()
+	ts := httptest.NewServer(server.azureHandler())
+	defer ts.Close()
+
+	t.Setenv("ARM_SAS_TOKEN", "")
+	t.Setenv("ARM_ACCESS_KEY", base64.StdEncoding.EncodeToString([]byte("secret")))
+
+	store, err := newAzureStore(&state.AzureState{StorageAccount: "account", ContainerName: "container"})
+	require.NoError(t, err)
+	store.client = ts.Client()
+	store.endpoint = ts.URL
+
+	testRemoteHandler(t, store)
+	for _, r := range server.requests {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey account:"))
+	}
+}
+
+func TestNewAzureStoreMissingCredentials(t *testing.T) {
+	t.Setenv("ARM_SAS_TOKEN", "")
+	t.Setenv("ARM_ACCESS_KEY", "")
+
+	_, err := newAzureStore(&state.AzureState{StorageAccount: "account", ContainerName: "container"})
+	assert.ErrorIs(t, err, errNoAzureCredentials)
+
+	// Without credentials for the hash storage, for example when authenticating with the az CLI, the hashes are
+	// stored locally instead of failing every command
+	h, err := Factory(context.Background(), &config.MachConfig{Global: config.GlobalConfig{
+		TerraformStateProvider: string(state.AzureType),
+		TerraformConfig: &config.TerraformConfig{RemoteState: map[string]any{
+			"storage_account": "account",
+			"container_name":  "container",
+		}},
+	}})
+	require.NoError(t, err)
+	assert.IsType(t, &JsonFileHandler{}, h)
+}
+
+// conflictingStore modifies the object right before the first write, as a concurrent run would do
+type conflictingStore struct {
+	objectStore
+	conflicted bool
+}
+
+func (s *conflictingStore) Put(ctx context.Context, key string, data []byte, version string) error {
+	if !s.conflicted {
+		s.conflicted = true
+		if err := s.objectStore.Put(ctx, key, []byte(`{"version":2,"hashes":{"be/payment":"other"}}`), version); err != nil {
+			return err
+		}
+	}
+	return s.objectStore.Put(ctx, key, data, version)
+}
+
+func TestRemoteHandlerConcurrentModification(t *testing.T) {
+	server := newFakeObjectServer()
+	ts := httptest.NewServer(server.gcsHandler())
+	defer ts.Close()
+
+	store := &conflictingStore{objectStore: &gcsStore{client: ts.Client(), endpoint: ts.URL, bucket: "bucket"}}
+	h := NewRemoteHandler(store, "hashes.json")
+
+	nl := newSiteComponent("nl", "payment")
+	require.NoError(t, h.Store(context.Background(), nl))
+
+	// Both the concurrent write and our own write should be retained
+	v, err := h.Fetch(context.Background(), newSiteComponent("be", "payment"))
+	require.NoError(t, err)
+	assert.Equal(t, "other", v)
+
+	nlHash, _ := nl.Hash()
+	v, err = h.Fetch(context.Background(), nl)
+	require.NoError(t, err)
+	assert.Equal(t, nlHash, v)
+}
+
+func TestObjectKey(t *testing.T) {
+	cfg := &config.MachConfig{Filename: "main.yml", Global: config.GlobalConfig{Environment: "test"}}
+	other := &config.MachConfig{Filename: "other.yml", Global: config.GlobalConfig{Environment: "test"}}
+	production := &config.MachConfig{Filename: "main.yml", Global: config.GlobalConfig{Environment: "production"}}
+
+	assert.True(t, strings.HasPrefix(objectKey(cfg), "mach-composer/hashes-"))
+	assert.Equal(t, objectKey(cfg), objectKey(cfg))
+	assert.NotEqual(t, objectKey(cfg), objectKey(other))
+	assert.NotEqual(t, objectKey(cfg), objectKey(production))
+}
+
+func TestFactoryRemoteHashKey(t *testing.T) {
+	newConfig := func(filename, prefix string) *config.MachConfig {
+		return &config.MachConfig{
+			Filename: filename,
+			Global: config.GlobalConfig{
+				Environment:            "test",
+				TerraformStateProvider: string(state.AwsType),
+				TerraformConfig: &config.TerraformConfig{RemoteState: map[string]any{
+					"bucket":     "bucket",
+					"key_prefix": prefix,
+					"region":     "eu-central-1",
+				}},
+			},
+		}
+	}
+
+	key := func(cfg *config.MachConfig) string {
+		h, err := Factory(context.Background(), cfg)
+		require.NoError(t, err)
+		require.IsType(t, &RemoteHandler{}, h)
+		return h.(*RemoteHandler).key
+	}
+
+	// The document is stored under the state prefix, and projects sharing the prefix do not share the document
+	main := newConfig("main.yml", "shared")
+	assert.Equal(t, "shared/"+objectKey(main), key(main))
+	assert.NotEqual(t, key(main), key(newConfig("other.yml", "shared")))
+	assert.Equal(t, "project/"+objectKey(main), key(newConfig("main.yml", "project")))
+}
@@ -0,0 +1,99 @@
+package hash
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/mach-composer/mach-composer-cli/internal/state"
+)
+
+// s3Store stores objects in the S3 bucket of the terraform state
+type s3Store struct {
+	client *s3.S3
+	bucket string
+}
+
+func newS3Store(s *state.AwsState) (*s3Store, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(s.Region)},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &aws.Config{}
+	if s.RoleARN != "" {
+		cfg.Credentials = stscreds.NewCredentials(sess, s.RoleARN)
+	}
+
+	return &s3Store{
+		client: s3.New(sess, cfg),
+		bucket: s.Bucket,
+	}, nil
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) ([]byte, string, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if s3StatusCode(err) == http.StatusNotFound {
+			return nil, "", errObjectNotFound
+		}
+		return nil, "", err
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, aws.StringValue(out.ETag), nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, data []byte, version string) error {
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	req.SetContext(ctx)
+
+	// S3 supports conditional writes through the standard HTTP precondition headers
+	if version == "" {
+		req.HTTPRequest.Header.Set("If-None-Match", "*")
+	} else {
+		req.HTTPRequest.Header.Set("If-Match", version)
+	}
+
+	if err := req.Send(); err != nil {
+		switch s3StatusCode(err) {
+		case http.StatusPreconditionFailed, http.StatusConflict:
+			return errVersionConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+func s3StatusCode(err error) int {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		return reqErr.StatusCode()
+	}
+	return 0
+}
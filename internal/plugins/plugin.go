@@ -19,6 +19,8 @@ type PluginHandler struct {
 	client    *plugin.Client
 	isRunning bool
 	Config    PluginConfig
+	// checksum is the checksum of the executable that was started
+	checksum []byte
 }
 
 func (p *PluginHandler) Close() {
@@ -27,6 +29,15 @@ func (p *PluginHandler) Close() {
 	p.client = nil
 }
 
+// ResolvedVersion returns the version of the plugin that is run. A plugin that is replaced by a local executable is
+// identified by the checksum of that executable instead, as its configured version does not describe what is run.
+func (p *PluginHandler) ResolvedVersion() string {
+	if p.Config.Replace != "" {
+		return fmt.Sprintf("%x", p.checksum)
+	}
+	return p.Config.Version
+}
+
 func (p *PluginHandler) Start(ctx context.Context) error {
 	if p.isRunning {
 		return nil
@@ -48,6 +59,7 @@ func (p *PluginHandler) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	p.checksum = executable.Checksum
 
 	p.client = plugin.NewClient(&plugin.ClientConfig{
 		HandshakeConfig: plugin.HandshakeConfig{
@@ -55,6 +55,15 @@ func (p *PluginRepository) All() []*PluginHandler {
 	return result
 }
 
+// Versions returns the resolved version of every plugin in the repository, keyed by the plugin name
+func (p *PluginRepository) Versions() map[string]string {
+	result := make(map[string]string, len(p.handlers))
+	for name, handler := range p.handlers {
+		result[name] = handler.ResolvedVersion()
+	}
+	return result
+}
+
 func (p *PluginRepository) Names(names ...string) []PluginHandler {
 	var result []PluginHandler
 
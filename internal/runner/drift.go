@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/rs/zerolog/log"
+)
+
+// DriftStatus is the outcome of the drift detection of a single node
+type DriftStatus string
+
+const (
+	DriftNone     DriftStatus = "no-drift"
+	DriftDetected DriftStatus = "drift"
+	DriftError    DriftStatus = "error"
+)
+
+// NodeDrift is the drift detection result of a single node
+type NodeDrift struct {
+	Path   string
+	Status DriftStatus
+	Error  error
+}
+
+// DriftReport contains the drift detection result of every node that was checked. It is safe for concurrent use.
+type DriftReport struct {
+	mu    sync.Mutex
+	Nodes []NodeDrift
+}
+
+func (r *DriftReport) add(path string, status DriftStatus, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Nodes = append(r.Nodes, NodeDrift{Path: path, Status: status, Error: err})
+}
+
+func (r *DriftReport) count(status DriftStatus) int {
+	c := 0
+	for _, n := range r.Nodes {
+		if n.Status == status {
+			c++
+		}
+	}
+	return c
+}
+
+// HasDrift returns true if drift was detected on any node
+func (r *DriftReport) HasDrift() bool {
+	return r.count(DriftDetected) > 0
+}
+
+// HasErrors returns true if drift detection failed on any node
+func (r *DriftReport) HasErrors() bool {
+	return r.count(DriftError) > 0
+}
+
+// Write renders the report as a table ordered by node path, followed by a summary
+func (r *DriftReport) Write(w io.Writer) {
+	sort.Slice(r.Nodes, func(i, j int) bool {
+		return r.Nodes[i].Path < r.Nodes[j].Path
+	})
+
+	var data [][]string
+	for _, n := range r.Nodes {
+		detail := ""
+		if n.Error != nil {
+			detail = n.Error.Error()
+		}
+		data = append(data, []string{n.Path, string(n.Status), detail})
+	}
+
+	cli.WriteTable(w, []string{"Path", "Status", "Error"}, data)
+	_, _ = fmt.Fprintf(w, "%d without drift, %d with drift, %d failed\n",
+		r.count(DriftNone), r.count(DriftDetected), r.count(DriftError))
+}
+
+// TerraformDrift plans every selected node, regardless of change detection, to find changes between the deployed
+// resources and the terraform state. Neither the saved plans nor the stored hashes are modified. Failures are recorded
+// in the report instead of aborting the run, so every node is checked.
+func (gr *GraphRunner) TerraformDrift(ctx context.Context, dg *graph.Graph, opts *DriftOptions) (*DriftReport, error) {
+	report := &DriftReport{}
+
+	err := gr.schedule(ctx, dg, func(ctx context.Context, n graph.Node) (string, error) {
+		drift, err := terraformDetectDrift(ctx, n, opts)
+		switch {
+		case err != nil:
+			log.Error().Err(err).Msgf("Failed to detect drift for %s", n.Identifier())
+			report.add(dg.RelativePath(n), DriftError, err)
+		case drift:
+			report.add(dg.RelativePath(n), DriftDetected, nil)
+		default:
+			report.add(dg.RelativePath(n), DriftNone, nil)
+		}
+		return "", nil
+	}, &runOptions{
+		IgnoreChangeDetection: true,
+		Targets:               opts.Targets,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func terraformDetectDrift(ctx context.Context, n graph.Node, opts *DriftOptions) (bool, error) {
+	if !terraformIsInitialized(n.Path()) || opts.ForceInit {
+		log.Info().Msgf("Running terraform init for %s", n.Path())
+		if _, err := terraform.Init(ctx, n.Path()); err != nil {
+			return false, err
+		}
+	}
+
+	canPlan, err := terraformCanPlan(ctx, n)
+	if err != nil {
+		return false, err
+	}
+	if !canPlan {
+		return false, fmt.Errorf("the outputs of one of its parents are missing")
+	}
+
+	return terraform.DetectDrift(ctx, n.Path(), opts.RefreshOnly)
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// driftResponder returns outputs, and plans with the exit code of the node at the path, defaulting to 0
+func driftResponder(exitCodes map[string]int) func(c terraform.RecordedCommand) (string, error) {
+	return func(c terraform.RecordedCommand) (string, error) {
+		switch c.Args[0] {
+		case "output":
+			return `{"name":{"sensitive":false,"type":"string","value":"value"}}`, nil
+		case "plan":
+			if code := exitCodes[c.Path]; code != 0 {
+				return "", &terraform.ExitError{Code: code}
+			}
+		}
+		return "", nil
+	}
+}
+
+func newDriftNode(t *testing.T, dir, name string, nodeType internalgraph.Type,
+	parents ...internalgraph.Node) *internalgraph.NodeMock {
+	p := filepath.Join(dir, name)
+	initializeNodes(t, p)
+
+	n := newNodeMock(name, p, nodeType)
+	n.On("Parents").Return(parents, nil)
+	return n
+}
+
+func TestGraphRunnerTerraformDrift(t *testing.T) {
+	dir := t.TempDir()
+
+	project := newNodeMock("main", dir, internalgraph.ProjectType)
+
+	site := newDriftNode(t, dir, "site-1", internalgraph.SiteType, project)
+	stable := newDriftNode(t, dir, "site-1/stable", internalgraph.SiteComponentType, site)
+	drifted := newDriftNode(t, dir, "site-1/drifted", internalgraph.SiteComponentType, site)
+	failed := newDriftNode(t, dir, "site-1/failed", internalgraph.SiteComponentType, site)
+
+	g := internalgraph.CreateGraphMock(
+		map[string]internalgraph.Node{
+			project.Path(): project,
+			site.Path():    site,
+			stable.Path():  stable,
+			drifted.Path(): drifted,
+			failed.Path():  failed,
+		},
+		project,
+		internalgraph.EdgeMock{Source: project.Path(), Target: site.Path()},
+		internalgraph.EdgeMock{Source: site.Path(), Target: stable.Path()},
+		internalgraph.EdgeMock{Source: site.Path(), Target: drifted.Path()},
+		internalgraph.EdgeMock{Source: site.Path(), Target: failed.Path()},
+	)
+
+	// No hash handler is set, as drift detection must not use the hash store
+	r := &GraphRunner{workers: 2, strategy: DependencyStrategy, outputMode: GroupedOutput}
+	ctx := terraform.WithExecutor(context.Background(), &terraform.RecordingExecutor{
+		Respond: driftResponder(map[string]int{drifted.Path(): 2, failed.Path(): 1}),
+	})
+	report, err := r.TerraformDrift(ctx, g, &DriftOptions{RefreshOnly: true})
+	require.NoError(t, err)
+
+	assert.True(t, report.HasDrift())
+	assert.True(t, report.HasErrors())
+
+	statuses := map[string]DriftStatus{}
+	for _, n := range report.Nodes {
+		statuses[n.Path] = n.Status
+	}
+	assert.Equal(t, map[string]DriftStatus{
+		"site-1":         DriftNone,
+		"site-1/stable":  DriftNone,
+		"site-1/drifted": DriftDetected,
+		"site-1/failed":  DriftError,
+	}, statuses)
+
+	for _, n := range []*internalgraph.NodeMock{site, stable, drifted, failed} {
+		assert.NoFileExists(t, filepath.Join(n.Path(), terraform.PlanFile))
+	}
+
+	var buf bytes.Buffer
+	report.Write(&buf)
+	assert.Contains(t, buf.String(), "2 without drift, 1 with drift, 1 failed")
+}
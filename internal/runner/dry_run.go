@@ -0,0 +1,30 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/rs/zerolog/log"
+)
+
+// dryRun wraps the executor so the terraform commands it runs are recorded instead of run, and logs them once the
+// executor has finished. The executor itself is responsible for skipping its other side effects, like storing hashes.
+func dryRun(g *graph.Graph, f executorFunc) executorFunc {
+	return func(ctx context.Context, n graph.Node) (string, error) {
+		recorder := &terraform.RecordingExecutor{}
+		_, err := f(terraform.WithExecutor(ctx, recorder), n)
+
+		commands := recorder.Commands()
+		if len(commands) == 0 {
+			log.Info().Msgf("[dry-run] No commands would be run for %s", g.RelativePath(n))
+		} else {
+			log.Info().Msgf("[dry-run] Commands that would be run for %s:", g.RelativePath(n))
+		}
+		for _, c := range commands {
+			log.Info().Msgf("[dry-run]   %s", c)
+		}
+
+		return "", err
+	}
+}
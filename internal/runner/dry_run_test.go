@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphRunnerDryRun(t *testing.T) {
+	dir := t.TempDir()
+	g := newTestGraph(t, dir, config.DeploymentSiteComponent, gitComponent("component"))
+
+	// The executor records every command that is actually run, which must not happen in a dry run
+	recorder := &terraform.RecordingExecutor{}
+	ctx := terraform.WithExecutor(context.Background(), recorder)
+
+	h := hash.NewMemoryMapHandler()
+	r := &GraphRunner{workers: 1, hash: h, batch: batcher.NaiveBatchFunc()}
+
+	require.NoError(t, r.TerraformPlan(ctx, g, &PlanOptions{DryRun: true}))
+	require.NoError(t, r.TerraformApply(ctx, g, &ApplyOptions{DryRun: true}))
+
+	assert.Empty(t, recorder.Commands())
+	for _, n := range g.Vertices() {
+		assert.NoFileExists(t, filepath.Join(n.Path(), terraform.PlanMetadataFile))
+	}
+
+	// No hashes are stored, so the nodes are still considered changed
+	for _, n := range g.Vertices() {
+		stored, err := h.Fetch(context.Background(), n)
+		require.NoError(t, err)
+		assert.Empty(t, stored)
+	}
+}
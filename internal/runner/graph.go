@@ -2,18 +2,21 @@ package runner
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/mach-composer/mach-composer-cli/internal/batcher"
 	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
 	"github.com/mach-composer/mach-composer-cli/internal/hash"
 	"github.com/mach-composer/mach-composer-cli/internal/terraform"
-	"github.com/mach-composer/mach-composer-cli/internal/utils"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/exp/maps"
 	"golang.org/x/sync/semaphore"
+	"os"
+	"slices"
 	"sort"
 	"sync"
+	"time"
 )
 
 type (
@@ -22,31 +25,133 @@ type (
 )
 
 // GraphRunner will run a set of commands on a graph of nodes. Untainted nodes (no changes) will be skipped.
-// The nodes are batched based on a batching function, and all nodes in the same batch will be run in parallel.
+// How the nodes are scheduled depends on the strategy. With the batch strategy the nodes are batched based on a
+// batching function, and all nodes in the same batch will be run in parallel. With the dependency strategy a node is
+// started as soon as all its parents have finished.
 type GraphRunner struct {
-	workers int
-	batch   batcher.BatchFunc
-	hash    hash.Handler
+	workers  int
+	batch    batcher.BatchFunc
+	hash     hash.Handler
+	strategy Strategy
+	// outputMode determines how the output of the commands is written. The output is interactive if it is not set
+	outputMode OutputMode
+	// retryPolicies determine when a failed node is run again. Nodes are attempted once if they are not set
+	retryPolicies *RetryPolicies
+	// protectedResources determine which resources may not be deleted or replaced by apply
+	protectedResources *ProtectedResources
+	// runTimeout is the maximum duration of a run, and nodeTimeout the maximum duration of every terraform command
+	// run on a node. Zero disables the timeout
+	runTimeout  time.Duration
+	nodeTimeout time.Duration
 }
 
-func NewGraphRunner(batcher batcher.BatchFunc, hashHandler hash.Handler, workers int) *GraphRunner {
+func NewGraphRunner(batcher batcher.BatchFunc, hashHandler hash.Handler, workers int, strategy Strategy) *GraphRunner {
 	return &GraphRunner{
-		workers: workers,
-		batch:   batcher,
-		hash:    hashHandler,
+		workers:  workers,
+		batch:    batcher,
+		hash:     hashHandler,
+		strategy: strategy,
 	}
 }
 
-func (gr *GraphRunner) run(ctx context.Context, g *graph.Graph, f executorFunc, ignoreChangeDetection bool) error {
-	if err := taintGraph(ctx, g, gr.hash); err != nil {
+// SetOutputMode sets how the output of the commands run on the nodes is written
+func (gr *GraphRunner) SetOutputMode(mode OutputMode) {
+	gr.outputMode = mode
+}
+
+// SetRetryPolicies sets the policies that determine when a failed node is run again
+func (gr *GraphRunner) SetRetryPolicies(p *RetryPolicies) {
+	gr.retryPolicies = p
+}
+
+// SetProtectedResources sets the resources that may not be deleted or replaced by apply
+func (gr *GraphRunner) SetProtectedResources(p *ProtectedResources) {
+	gr.protectedResources = p
+}
+
+func (gr *GraphRunner) run(ctx context.Context, g *graph.Graph, f executorFunc, opts *runOptions) error {
+	ctx, cancel := gr.withRunTimeout(withOutputCache(ctx))
+	defer cancel()
+
+	digest := terraformOutputsDigest(gr.hash)
+	if opts.StoredOutputsDigest {
+		digest = storedOutputsDigest(gr.hash)
+	}
+
+	if err := taintGraph(ctx, g, gr.hash, digest); err != nil {
+		return err
+	}
+
+	return gr.dispatch(ctx, g, f, opts)
+}
+
+// schedule runs the executor on the nodes according to the strategy, without updating the change detection state
+func (gr *GraphRunner) schedule(ctx context.Context, g *graph.Graph, f executorFunc, opts *runOptions) error {
+	ctx, cancel := gr.withRunTimeout(withOutputCache(ctx))
+	defer cancel()
+
+	return gr.dispatch(ctx, g, f, opts)
+}
+
+// dispatch runs the nodes using the strategy of the runner. Once the run has finished, either normally or because it
+// was cancelled, the nodes that might still hold a state lock are reported.
+func (gr *GraphRunner) dispatch(ctx context.Context, g *graph.Graph, f executorFunc, opts *runOptions) error {
+	report := newRunReport(g)
+	defer report.warnLocks()
+
+	switch gr.strategy {
+	case DependencyStrategy:
+		return gr.runDependencies(ctx, g, f, opts, report)
+	default:
+		return gr.runBatches(ctx, g, f, opts, report)
+	}
+}
+
+// skipStatus determines whether a node should be skipped, either because it is not selected, because it has no
+// changes or because it has no saved plan while one is required. An empty status is returned if the node should be run.
+func skipStatus(n graph.Node, targets map[string]bool, opts *runOptions) NodeStatus {
+	if targets != nil && !targets[n.Path()] {
+		log.Info().Msgf("Skipping %s because it is not selected", n.Identifier())
+		return StatusNotSelected
+	}
+
+	if n.Tainted() == false && opts.IgnoreChangeDetection == false {
+		log.Info().Msgf("Skipping %s because it has no changes", n.Identifier())
+		return StatusUnchanged
+	}
+
+	// A plan that cannot be checked is left to the executor, which reports the error
+	if opts.RequirePlan {
+		if ok, err := terraform.HasPlan(n.Path()); err == nil && !ok {
+			log.Warn().Msgf("Skipping %s because it has no saved plan", n.Identifier())
+			return StatusNoPlan
+		}
+	}
+
+	return ""
+}
+
+func (gr *GraphRunner) runBatches(ctx context.Context, g *graph.Graph, f executorFunc, opts *runOptions, report *runReport) error {
+	targets := opts.targetSet()
+
+	upstream, _, err := opts.dependencyMaps(g)
+	if err != nil {
 		return err
 	}
 
 	batches := gr.batch(g)
 
+	var errors []error
 	keys := maps.Keys(batches)
 	sort.Ints(keys)
-	for i, k := range keys[1:] {
+
+	// The first batch only contains the start node, which is never run
+	keys = keys[1:]
+	if opts.Reverse {
+		slices.Reverse(keys)
+	}
+
+	for i, k := range keys {
 		log.Info().Msgf("Running batch %d with %d nodes", i, len(batches[k]))
 
 		errChan := make(chan error, len(batches[k]))
@@ -54,13 +159,20 @@ func (gr *GraphRunner) run(ctx context.Context, g *graph.Graph, f executorFunc,
 		sem := semaphore.NewWeighted(int64(gr.workers))
 
 		for _, n := range batches[k] {
-			if n.Tainted() == false && ignoreChangeDetection == false {
-				log.Info().Msgf("Skipping %s because it has no changes", n.Identifier())
+			if status := skipStatus(n, targets, opts); status != "" {
+				report.set(n, status)
+				continue
+			}
+
+			if report.blocked(upstream[n.Path()], upstream) {
+				log.Warn().Msgf("Skipping %s because one of its dependencies failed", n.Identifier())
+				report.set(n, StatusSkippedByDependency)
 				continue
 			}
 
 			if err := sem.Acquire(ctx, 1); err != nil {
-				return err
+				wg.Wait()
+				return context.Cause(ctx)
 			}
 			wg.Add(1)
 			go func(ctx context.Context, n graph.Node) {
@@ -69,11 +181,13 @@ func (gr *GraphRunner) run(ctx context.Context, g *graph.Graph, f executorFunc,
 
 				log.Info().Msgf("Running command on %s", n.Identifier())
 
-				out, err := f(ctx, n)
+				out, err := gr.executeWithRetry(ctx, g, f, n, report)
 				if err != nil {
+					report.set(n, failureStatus(err))
 					errChan <- err
 					return
 				}
+				report.set(n, StatusSucceeded)
 				log.Info().Msg(out)
 			}(ctx, n)
 		}
@@ -81,12 +195,21 @@ func (gr *GraphRunner) run(ctx context.Context, g *graph.Graph, f executorFunc,
 		close(errChan)
 
 		if len(errChan) > 0 {
-			var errors []error
+			var batchErrors []error
 			for err := range errChan {
-				errors = append(errors, err)
+				batchErrors = append(batchErrors, err)
 			}
 
-			return cli.NewGroupedError(fmt.Sprintf("batch run %d failed (%d errors)", i, len(errors)), errors)
+			if !opts.KeepGoing {
+				if report.noteworthy() {
+					report.Write(opts.reportWriter())
+				}
+				return cli.NewGroupedError(fmt.Sprintf("batch run %d failed (%d errors)", i, len(batchErrors)), batchErrors)
+			}
+
+			log.Warn().Msgf("Batch %d failed (%d errors), continuing with the independent nodes", i, len(batchErrors))
+			errors = append(errors, batchErrors...)
+			continue
 		}
 
 		log.Info().Msgf("Finished batch %d", i)
@@ -94,11 +217,42 @@ func (gr *GraphRunner) run(ctx context.Context, g *graph.Graph, f executorFunc,
 
 	log.Info().Msgf("Finished all batches")
 
+	return finishRun(report, errors, opts)
+}
+
+// finishRun prints the report when running in keep-going mode or when nodes were retried or timed out, and returns the
+// errors collected during the run
+func finishRun(report *runReport, errors []error, opts *runOptions) error {
+	if opts.KeepGoing || report.noteworthy() {
+		report.Write(opts.reportWriter())
+	}
+
+	if len(errors) > 0 {
+		return cli.NewGroupedError(fmt.Sprintf("run failed (%d errors)", len(errors)), errors)
+	}
+
 	return nil
 }
 
 func (gr *GraphRunner) TerraformApply(ctx context.Context, dg *graph.Graph, opts *ApplyOptions) error {
-	if err := gr.run(ctx, dg, func(ctx context.Context, n graph.Node) (string, error) {
+	// Nothing is applied in a dry run, so the layout changes are only warned about
+	if !opts.Destroy {
+		if err := gr.CheckLayoutChanges(ctx, dg, opts.AllowLayoutChange || opts.DryRun); err != nil {
+			return err
+		}
+	}
+
+	f := func(ctx context.Context, n graph.Node) (string, error) {
+		if opts.RequirePlan {
+			ok, err := terraform.HasPlan(n.Path())
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				return "", fmt.Errorf("the saved plan for %s was removed before it could be applied", n.Path())
+			}
+		}
+
 		if !terraformIsInitialized(n.Path()) || opts.ForceInit {
 			log.Info().Msgf("Running terraform init for %s", n.Path())
 			if out, err := terraform.Init(ctx, n.Path()); err != nil {
@@ -108,26 +262,112 @@ func (gr *GraphRunner) TerraformApply(ctx context.Context, dg *graph.Graph, opts
 			log.Info().Msgf("Skipping terraform init for %s", n.Path())
 		}
 
+		if opts.DryRun {
+			return terraform.Apply(ctx, n.Path(), opts.Destroy, opts.AutoApprove)
+		}
+
+		if opts.Destroy {
+			// Saved plans are never destroy plans, and are of no use once the resources are destroyed
+			if err := terraform.RemovePlan(n.Path()); err != nil {
+				return "", err
+			}
+		} else if err := checkPlan(n, opts.ReplanStale); err != nil {
+			return "", err
+		} else if isRetry(ctx) {
+			if out, err := replan(ctx, n); err != nil {
+				return out, err
+			}
+		}
+
+		if !opts.Destroy && !opts.AllowDestroy {
+			if err := gr.checkProtectedResources(ctx, n, opts.AutoApprove); err != nil {
+				return "", err
+			}
+		}
+
 		out, err := terraform.Apply(ctx, n.Path(), opts.Destroy, opts.AutoApprove)
+		// The outputs of the node might have changed, even if the apply failed halfway
+		invalidateOutput(ctx, n.Path())
 		if err != nil {
 			return out, err
 		}
 
+		if err = terraform.RemovePlan(n.Path()); err != nil {
+			log.Warn().Err(err).Msgf("Failed to remove the applied plan of %s", n.Identifier())
+		}
+
+		if opts.Destroy {
+			log.Info().Msgf("Removing hash for %s", n.Path())
+			if err = gr.hash.Delete(ctx, n); err != nil {
+				log.Warn().Err(err).Msgf("Failed to remove hash for %s", n.Identifier())
+			}
+			return out, nil
+		}
+
+		// The referenced outputs might have changed by applying the parents, so they are read again before storing
+		if err = terraformOutputsDigest(gr.hash)(ctx, dg, n); err != nil {
+			log.Warn().Err(err).Msgf("Failed to compute the outputs digest for %s", n.Identifier())
+		}
+
 		log.Info().Msgf("Storing new hash for %s", n.Path())
 		if err = gr.hash.Store(ctx, n); err != nil {
 			log.Warn().Err(err).Msgf("Failed to store hash for %s", n.Identifier())
 		}
 		return out, nil
+	}
 
-	}, opts.IgnoreChangeDetection); err != nil {
+	if opts.DryRun {
+		f = dryRun(dg, f)
+	}
+
+	if err := gr.run(ctx, dg, f, &runOptions{
+		// When destroying, every selected node is destroyed regardless of changes, starting with the dependents
+		IgnoreChangeDetection: opts.IgnoreChangeDetection || opts.Destroy,
+		Reverse:               opts.Destroy,
+		Targets:               opts.Targets,
+		KeepGoing:             opts.KeepGoing,
+		// The commands are only recorded in a dry run, so the outputs of the parents cannot be read either
+		StoredOutputsDigest: opts.DryRun,
+		RequirePlan:         opts.RequirePlan,
+	}); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// DestroyOrder returns the nodes that are destroyed by a destroy run, in the order they are destroyed: dependents
+// before their dependencies. If targets are given only these nodes are returned.
+func (gr *GraphRunner) DestroyOrder(dg *graph.Graph, targets graph.Vertices) []graph.Node {
+	selected := (&runOptions{Targets: targets}).targetSet()
+
+	batches := gr.batch(dg)
+	keys := maps.Keys(batches)
+	sort.Sort(sort.Reverse(sort.IntSlice(keys)))
+
+	var nodes []graph.Node
+	for _, k := range keys {
+		batch := slices.Clone(batches[k])
+		sort.Slice(batch, func(i, j int) bool {
+			return batch[i].Path() < batch[j].Path()
+		})
+
+		for _, n := range batch {
+			if n.Path() == dg.StartNode.Path() || (selected != nil && !selected[n.Path()]) {
+				continue
+			}
+			nodes = append(nodes, n)
+		}
+	}
+
+	return nodes
+}
+
 func (gr *GraphRunner) TerraformPlan(ctx context.Context, dg *graph.Graph, opts *PlanOptions) error {
-	if err := gr.run(ctx, dg, func(ctx context.Context, n graph.Node) (string, error) {
+	// Planning does not change any resources, so the layout changes are only warned about
+	_ = gr.CheckLayoutChanges(ctx, dg, true)
+
+	f := func(ctx context.Context, n graph.Node) (string, error) {
 		if !terraformIsInitialized(n.Path()) || opts.ForceInit {
 			log.Info().Msgf("Running terraform init for %s", n.Path())
 			if out, err := terraform.Init(ctx, n.Path()); err != nil {
@@ -137,6 +377,11 @@ func (gr *GraphRunner) TerraformPlan(ctx context.Context, dg *graph.Graph, opts
 			log.Info().Msgf("Skipping terraform init for %s", n.Path())
 		}
 
+		// The outputs of the parents are not available in a dry run, so planning is assumed to be possible
+		if opts.DryRun {
+			return terraform.Plan(ctx, n.Path(), opts.Lock)
+		}
+
 		canPlan, err := terraformCanPlan(ctx, n)
 		if err != nil {
 			return "", err
@@ -147,8 +392,24 @@ func (gr *GraphRunner) TerraformPlan(ctx context.Context, dg *graph.Graph, opts
 			return "", nil
 		}
 
-		return terraform.Plan(ctx, n.Path(), opts.Lock)
-	}, opts.IgnoreChangeDetection); err != nil {
+		out, err := terraform.Plan(ctx, n.Path(), opts.Lock)
+		if err != nil {
+			return out, err
+		}
+
+		return out, storePlanMetadata(n)
+	}
+
+	if opts.DryRun {
+		f = dryRun(dg, f)
+	}
+
+	if err := gr.run(ctx, dg, f, &runOptions{
+		IgnoreChangeDetection: opts.IgnoreChangeDetection,
+		Targets:               opts.Targets,
+		KeepGoing:             opts.KeepGoing,
+		StoredOutputsDigest:   opts.DryRun,
+	}); err != nil {
 		return err
 	}
 
@@ -161,8 +422,12 @@ func (gr *GraphRunner) TerraformProxy(ctx context.Context, dg *graph.Graph, opts
 			return "", fmt.Errorf("terraform is not initialized for %s. Please run init beforehand", n.Path())
 		}
 
-		return utils.RunTerraform(ctx, n.Path(), false, opts.Command...)
-	}, opts.IgnoreChangeDetection); err != nil {
+		return terraform.Run(ctx, n.Path(), false, opts.Command...)
+	}, &runOptions{
+		IgnoreChangeDetection: opts.IgnoreChangeDetection,
+		Targets:               opts.Targets,
+		StoredOutputsDigest:   true,
+	}); err != nil {
 		return err
 	}
 
@@ -170,6 +435,10 @@ func (gr *GraphRunner) TerraformProxy(ctx context.Context, dg *graph.Graph, opts
 }
 
 func (gr *GraphRunner) TerraformShow(ctx context.Context, dg *graph.Graph, opts *ShowPlanOptions) error {
+	if opts.JSON {
+		return gr.terraformShowJSON(ctx, dg, opts)
+	}
+
 	if err := gr.run(ctx, dg, func(ctx context.Context, n graph.Node) (string, error) {
 		if !terraformIsInitialized(n.Path()) || opts.ForceInit {
 			log.Info().Msgf("Running terraform init for %s", n.Path())
@@ -181,17 +450,55 @@ func (gr *GraphRunner) TerraformShow(ctx context.Context, dg *graph.Graph, opts
 		}
 
 		return terraform.Show(ctx, n.Path(), opts.NoColor)
-	}, opts.IgnoreChangeDetection); err != nil {
+	}, &runOptions{
+		IgnoreChangeDetection: opts.IgnoreChangeDetection,
+		Targets:               opts.Targets,
+		StoredOutputsDigest:   true,
+	}); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (gr *GraphRunner) TerraformInit(ctx context.Context, dg *graph.Graph) error {
+// terraformShowJSON summarizes the plans of all nodes into a single JSON document keyed by the node path relative to
+// the project. Terraform is not initialized in this mode, as its output would end up in the document.
+func (gr *GraphRunner) terraformShowJSON(ctx context.Context, dg *graph.Graph, opts *ShowPlanOptions) error {
+	var mu sync.Mutex
+	summaries := map[string]*terraform.PlanSummary{}
+
 	if err := gr.run(ctx, dg, func(ctx context.Context, n graph.Node) (string, error) {
+		if !terraformIsInitialized(n.Path()) {
+			return "", fmt.Errorf("terraform is not initialized for %s. Please run init beforehand", n.Path())
+		}
+
+		summary, err := planSummary(ctx, n)
+		if err != nil {
+			return "", err
+		}
+
+		mu.Lock()
+		summaries[dg.RelativePath(n)] = summary
+		mu.Unlock()
+
+		return "", nil
+	}, &runOptions{
+		IgnoreChangeDetection: opts.IgnoreChangeDetection,
+		Targets:               opts.Targets,
+		StoredOutputsDigest:   true,
+	}); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(summaries)
+}
+
+func (gr *GraphRunner) TerraformInit(ctx context.Context, dg *graph.Graph) error {
+	if err := gr.schedule(ctx, dg, func(ctx context.Context, n graph.Node) (string, error) {
 		return terraform.Init(ctx, n.Path())
-	}, true); err != nil {
+	}, &runOptions{IgnoreChangeDetection: true}); err != nil {
 		return err
 	}
 
@@ -70,7 +70,7 @@ func TestGraphRunnerMultipleLevels(t *testing.T) {
 	err := runner.run(context.Background(), graph, func(ctx context.Context, node internalgraph.Node) (string, error) {
 		called = append(called, node.Identifier())
 		return "", nil
-	}, false)
+	}, &runOptions{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"component-2", "component-3"}, called)
@@ -132,7 +132,7 @@ func TestGraphRunnerError(t *testing.T) {
 			return "", assert.AnError
 		}
 		return "", nil
-	}, false)
+	}, &runOptions{})
 
 	cliErr := &cli.GroupedError{}
 
@@ -142,3 +142,56 @@ func TestGraphRunnerError(t *testing.T) {
 	assert.Len(t, cliErr.Errors, 1)
 	assert.Equal(t, assert.AnError, cliErr.Errors[0])
 }
+
+func TestGraphRunnerTargets(t *testing.T) {
+	project := new(internalgraph.NodeMock)
+	project.On("Identifier").Return("main")
+	project.On("Path").Return("main")
+	project.On("Hash").Return("main", nil)
+	project.On("Type").Return(internalgraph.ProjectType)
+
+	site := new(internalgraph.NodeMock)
+	site.On("Identifier").Return("site-1")
+	site.On("Path").Return("site-1")
+	site.On("Hash").Return("site-1", nil)
+	site.On("Type").Return(internalgraph.SiteType)
+
+	component1 := new(internalgraph.NodeMock)
+	component1.On("Identifier").Return("component-1")
+	component1.On("Path").Return("component-1")
+	component1.On("Hash").Return("component-1", nil)
+	component1.On("Type").Return(internalgraph.SiteComponentType)
+
+	component2 := new(internalgraph.NodeMock)
+	component2.On("Identifier").Return("component-2")
+	component2.On("Path").Return("component-2")
+	component2.On("Hash").Return("component-2", nil)
+	component2.On("Type").Return(internalgraph.SiteComponentType)
+
+	graph := internalgraph.CreateGraphMock(
+		map[string]internalgraph.Node{
+			"main":        project,
+			"site-1":      site,
+			"component-1": component1,
+			"component-2": component2,
+		},
+		project,
+		internalgraph.EdgeMock{Source: "main", Target: "site-1"},
+		internalgraph.EdgeMock{Source: "site-1", Target: "component-1"},
+		internalgraph.EdgeMock{Source: "component-1", Target: "component-2"},
+	)
+
+	runner := GraphRunner{workers: 1}
+	runner.hash = hash.NewMemoryMapHandler()
+	runner.batch = batcher.NaiveBatchFunc()
+
+	var called []string
+
+	err := runner.run(context.Background(), graph, func(ctx context.Context, node internalgraph.Node) (string, error) {
+		called = append(called, node.Identifier())
+		return "", nil
+	}, &runOptions{Targets: internalgraph.Vertices{site, component2}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"site-1", "component-2"}, called)
+}
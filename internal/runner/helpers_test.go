@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestGraph creates the deployment graph of the project main with a single site, site-1, in the given directory.
+// The project and the site are deployed with the given type, as are the components that do not set their own.
+func newTestGraph(t *testing.T, dir string, deploymentType config.DeploymentType,
+	components ...config.SiteComponentConfig) *graph.Graph {
+	for i, c := range components {
+		if c.Deployment == nil {
+			components[i].Deployment = &config.Deployment{Type: deploymentType}
+		}
+	}
+
+	g, err := graph.ToDeploymentGraph(&config.MachConfig{
+		Filename:     "main",
+		MachComposer: config.MachComposer{Deployment: config.Deployment{Type: deploymentType}},
+		Sites: []config.SiteConfig{
+			{
+				Identifier: "site-1",
+				Deployment: &config.Deployment{Type: deploymentType},
+				Components: components,
+			},
+		},
+	}, dir)
+	require.NoError(t, err)
+	return g
+}
+
+// gitComponent returns the config of a component with the given name that is sourced from git
+func gitComponent(name string) config.SiteComponentConfig {
+	return config.SiteComponentConfig{
+		Name: name,
+		Definition: &config.ComponentConfig{
+			Name:    name,
+			Source:  config.Source("git::https://github.com/example/" + name),
+			Version: "1.0.0",
+		},
+	}
+}
+
+// testNode returns the node of the given type in the graph, which must be the only node of that type
+func testNode(t *testing.T, g *graph.Graph, nodeType graph.Type) graph.Node {
+	var result []graph.Node
+	for _, n := range g.Vertices() {
+		if n.Type() == nodeType {
+			result = append(result, n)
+		}
+	}
+	require.Len(t, result, 1, "expected a single node of type %s", nodeType)
+	return result[0]
+}
+
+// initializeNodes creates a terraform lock file in the given directories, so the nodes in them are considered
+// initialized and terraform init is not run
+func initializeNodes(t *testing.T, paths ...string) {
+	for _, p := range paths {
+		require.NoError(t, os.MkdirAll(p, 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(p, ".terraform.lock.hcl"), nil, 0600))
+	}
+}
+
+// newNodeMock creates a node mock with the given identifier, path and type. Other calls have to be set up by the caller.
+func newNodeMock(identifier, path string, nodeType graph.Type) *graph.NodeMock {
+	n := new(graph.NodeMock)
+	n.On("Identifier").Return(identifier)
+	n.On("Path").Return(path)
+	n.On("Type").Return(nodeType)
+	return n
+}
@@ -0,0 +1,235 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/rs/zerolog/log"
+)
+
+// LayoutChange is a change of the deployment type of a component since it was last stored
+type LayoutChange struct {
+	Site      string
+	Component string
+	From      config.DeploymentType
+	To        config.DeploymentType
+}
+
+// LayoutChanges compares the deployment type of every component to the one recorded when its hash was last stored.
+// Components that were never stored are not considered changed.
+func (gr *GraphRunner) LayoutChanges(ctx context.Context, dg *graph.Graph) ([]LayoutChange, error) {
+	recorded, err := gr.hash.FetchLayout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []LayoutChange
+	check := func(sc *graph.SiteComponent, current config.DeploymentType) {
+		if previous, ok := recorded[hash.Key(sc)]; ok && previous != current {
+			changes = append(changes, LayoutChange{
+				Site:      sc.SiteConfig.Identifier,
+				Component: sc.Identifier(),
+				From:      previous,
+				To:        current,
+			})
+		}
+	}
+
+	for _, n := range dg.Vertices() {
+		switch v := n.(type) {
+		case *graph.Site:
+			for _, nested := range v.NestedNodes {
+				check(nested, config.DeploymentSite)
+			}
+		case *graph.SiteComponent:
+			check(v, config.DeploymentSiteComponent)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Site != changes[j].Site {
+			return changes[i].Site < changes[j].Site
+		}
+		return changes[i].Component < changes[j].Component
+	})
+	return changes, nil
+}
+
+// LayoutChangesError is returned if the deployment type of components changed since they were last applied
+type LayoutChangesError struct {
+	Changes []LayoutChange
+}
+
+func (e *LayoutChangesError) Error() string {
+	changes := make([]string, len(e.Changes))
+	for i, c := range e.Changes {
+		changes[i] = fmt.Sprintf("%s/%s from %s to %s", c.Site, c.Component, c.From, c.To)
+	}
+	return fmt.Sprintf("the deployment type of components changed (%s), migrate their state with mach-composer "+
+		"migrate-state first, or use --allow-layout-change to destroy and recreate their resources",
+		strings.Join(changes, ", "))
+}
+
+// CheckLayoutChanges returns a LayoutChangesError if the deployment type of components changed, as applying would
+// destroy and recreate their resources unless their state is migrated. If allowed the changes are only warned about.
+func (gr *GraphRunner) CheckLayoutChanges(ctx context.Context, dg *graph.Graph, allow bool) error {
+	changes, err := gr.LayoutChanges(ctx, dg)
+	if err != nil {
+		log.Debug().Err(err).Msg("Unable to detect deployment type changes")
+		return nil
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if !allow {
+		return &LayoutChangesError{Changes: changes}
+	}
+
+	for _, c := range changes {
+		log.Warn().Msgf("The deployment type of component %s in site %s changed from %s to %s. Its resources will be "+
+			"destroyed and recreated unless its state is migrated with mach-composer migrate-state", c.Component, c.Site,
+			c.From, c.To)
+	}
+	return nil
+}
+
+// MigrateState moves the resources of components that changed from a site deployment to a separate deployment from
+// the site state to the state of the component. The states are pulled to local files, modified, and pushed back.
+func (gr *GraphRunner) MigrateState(ctx context.Context, dg *graph.Graph, opts *MigrateStateOptions) error {
+	changes, err := gr.LayoutChanges(ctx, dg)
+	if err != nil {
+		return err
+	}
+
+	// All changes are validated first, so no state is modified if any of them cannot be migrated
+	var selected []LayoutChange
+	for _, c := range changes {
+		if len(opts.Components) > 0 && !slices.Contains(opts.Components, c.Component) {
+			continue
+		}
+
+		if c.From != config.DeploymentSite || c.To != config.DeploymentSiteComponent {
+			return fmt.Errorf("migrating component %s in site %s from %s to %s is not supported", c.Component, c.Site,
+				c.From, c.To)
+		}
+		selected = append(selected, c)
+	}
+
+	if len(selected) == 0 {
+		log.Info().Msg("No components need to be migrated")
+		return nil
+	}
+
+	for _, c := range selected {
+		if err = gr.migrateComponentState(ctx, dg, c, opts); err != nil {
+			return fmt.Errorf("failed to migrate the state of component %s in site %s: %w", c.Component, c.Site, err)
+		}
+	}
+	return nil
+}
+
+func (gr *GraphRunner) migrateComponentState(ctx context.Context, dg *graph.Graph, c LayoutChange, opts *MigrateStateOptions) error {
+	target, err := graph.ResolveComponentState(dg, c.Site, c.Component)
+	if err != nil {
+		return err
+	}
+	site := target.Node.Ancestor()
+
+	for _, n := range []graph.Node{site, target.Node} {
+		if !terraformIsInitialized(n.Path()) || opts.ForceInit {
+			log.Info().Msgf("Running terraform init for %s", n.Path())
+			if _, err = terraform.Init(ctx, n.Path()); err != nil {
+				return err
+			}
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "mach-composer-migrate-")
+	if err != nil {
+		return err
+	}
+	// The pulled states are only kept when pushing them fails
+	keep := false
+	defer func() {
+		if !keep {
+			_ = os.RemoveAll(dir)
+		}
+	}()
+	siteState := filepath.Join(dir, "site.tfstate")
+	componentState := filepath.Join(dir, "component.tfstate")
+
+	if err = pullState(ctx, site.Path(), siteState); err != nil {
+		return err
+	}
+	if err = pullState(ctx, target.Node.Path(), componentState); err != nil {
+		return err
+	}
+
+	module := fmt.Sprintf("module.%s", c.Component)
+	addresses, err := terraform.StateList(ctx, site.Path(), siteState, module)
+	if err != nil {
+		return err
+	}
+
+	writeMigration(os.Stdout, dg, c, site, target.Node, addresses, opts.DryRun)
+	if opts.DryRun {
+		return nil
+	}
+
+	if len(addresses) > 0 {
+		if _, err = terraform.StateMoveToFile(ctx, site.Path(), siteState, componentState, module, module); err != nil {
+			return err
+		}
+
+		// The component state is pushed first, so the resources are never missing from both states. The pulled
+		// states are kept when pushing fails, so they can be restored manually.
+		if _, err = terraform.StatePush(ctx, target.Node.Path(), componentState); err != nil {
+			log.Error().Msgf("Pushing the state failed; the local states are kept in %s", dir)
+			keep = true
+			return err
+		}
+		if _, err = terraform.StatePush(ctx, site.Path(), siteState); err != nil {
+			log.Error().Msgf("Pushing the state failed; the local states are kept in %s", dir)
+			keep = true
+			return err
+		}
+	}
+
+	return gr.hash.StoreLayout(ctx, hash.Key(target.Node), c.To)
+}
+
+// pullState writes the state of the node to the file. Nothing is written if the node has no state yet.
+func pullState(ctx context.Context, path, file string) error {
+	state, err := terraform.StatePull(ctx, path)
+	if err != nil {
+		return err
+	}
+	if state == "" {
+		return nil
+	}
+	return os.WriteFile(file, []byte(state), 0600)
+}
+
+func writeMigration(w io.Writer, dg *graph.Graph, c LayoutChange, from, to graph.Node, addresses []string, dryRun bool) {
+	verb := "Moving"
+	if dryRun {
+		verb = "Would move"
+	}
+
+	_, _ = fmt.Fprintf(w, "%s %d resources of component %s from %s to %s\n", verb, len(addresses), c.Component,
+		dg.RelativePath(from), dg.RelativePath(to))
+	for _, a := range addresses {
+		_, _ = fmt.Fprintf(w, "  %s\n", a)
+	}
+}
@@ -0,0 +1,181 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stateResponder keeps the "remote" states in the map, keyed by the path of the node, and handles the state commands
+// on the local state files like terraform does
+func stateResponder(t *testing.T, remote map[string]string) func(c terraform.RecordedCommand) (string, error) {
+	// moveLines moves the lines of the file that start with the prefix to the other file
+	moveLines := func(from, to, prefix string) {
+		data, err := os.ReadFile(from)
+		require.NoError(t, err)
+
+		var kept, moved []string
+		for _, line := range strings.SplitAfter(string(data), "\n") {
+			if strings.HasPrefix(line, prefix) {
+				moved = append(moved, line)
+			} else if line != "" {
+				kept = append(kept, line)
+			}
+		}
+		require.NoError(t, os.WriteFile(from, []byte(strings.Join(kept, "")), 0600))
+		require.NoError(t, os.WriteFile(to, []byte(strings.Join(moved, "")), 0600))
+	}
+
+	var mu sync.Mutex
+	return func(c terraform.RecordedCommand) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(c.Args) < 2 {
+			return "", nil
+		}
+		switch strings.Join(c.Args[:2], " ") {
+		case "state pull":
+			return remote[c.Path], nil
+		case "state push":
+			data, err := os.ReadFile(c.Args[2])
+			require.NoError(t, err)
+			remote[c.Path] = string(data)
+		case "state list":
+			data, err := os.ReadFile(strings.TrimPrefix(c.Args[2], "-state="))
+			require.NoError(t, err)
+
+			var lines []string
+			for _, line := range strings.Split(string(data), "\n") {
+				if strings.HasPrefix(line, c.Args[3]+".") {
+					lines = append(lines, line)
+				}
+			}
+			return strings.Join(lines, "\n"), nil
+		case "state mv":
+			moveLines(strings.TrimPrefix(c.Args[2], "-state="), strings.TrimPrefix(c.Args[3], "-state-out="), c.Args[4]+".")
+		}
+		return "", nil
+	}
+}
+
+func TestGraphRunnerLayoutChanges(t *testing.T) {
+	g := newStateTestGraph(t)
+	h := &hash.MemoryMap{Layout: hash.Layout{
+		"site-1/nested":   config.DeploymentSite,
+		"site-1/separate": config.DeploymentSite,
+	}}
+	r := &GraphRunner{hash: h}
+
+	changes, err := r.LayoutChanges(context.Background(), g)
+	require.NoError(t, err)
+	assert.Equal(t, []LayoutChange{
+		{Site: "site-1", Component: "separate", From: config.DeploymentSite, To: config.DeploymentSiteComponent},
+	}, changes)
+}
+
+func TestGraphRunnerMigrateState(t *testing.T) {
+	g := newStateTestGraph(t)
+	sitePath := filepath.Join(g.StartNode.Path(), "site-1")
+	componentPath := filepath.Join(sitePath, "separate")
+	remote := map[string]string{
+		sitePath: "module.nested.aws_s3_bucket.main\nmodule.separate.aws_s3_bucket.main\n",
+	}
+	ctx := terraform.WithExecutor(context.Background(), &terraform.RecordingExecutor{
+		Respond: stateResponder(t, remote),
+	})
+
+	h := &hash.MemoryMap{Layout: hash.Layout{"site-1/separate": config.DeploymentSite}}
+	r := &GraphRunner{hash: h}
+
+	// A dry run does not modify the states
+	require.NoError(t, r.MigrateState(ctx, g, &MigrateStateOptions{DryRun: true}))
+	assert.NotContains(t, remote, componentPath)
+	assert.Equal(t, config.DeploymentSite, h.Layout["site-1/separate"])
+
+	require.NoError(t, r.MigrateState(ctx, g, &MigrateStateOptions{}))
+	assert.Equal(t, "module.nested.aws_s3_bucket.main\n", remote[sitePath])
+	assert.Equal(t, "module.separate.aws_s3_bucket.main\n", remote[componentPath])
+
+	// The new layout is recorded, so the component is not migrated again
+	assert.Equal(t, config.DeploymentSiteComponent, h.Layout["site-1/separate"])
+	changes, err := r.LayoutChanges(ctx, g)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestGraphRunnerMigrateStateUnsupported(t *testing.T) {
+	g := newStateTestGraph(t)
+	h := &hash.MemoryMap{Layout: hash.Layout{"site-1/nested": config.DeploymentSiteComponent}}
+	r := &GraphRunner{hash: h}
+
+	err := r.MigrateState(context.Background(), g, &MigrateStateOptions{})
+	assert.ErrorContains(t, err, "is not supported")
+
+	// No state is modified when any of the changes cannot be migrated
+	h.Layout["site-1/separate"] = config.DeploymentSite
+	recorder := &terraform.RecordingExecutor{}
+	ctx := terraform.WithExecutor(context.Background(), recorder)
+
+	err = r.MigrateState(ctx, g, &MigrateStateOptions{})
+	assert.ErrorContains(t, err, "is not supported")
+	assert.Empty(t, recorder.Commands())
+	assert.Equal(t, config.DeploymentSite, h.Layout["site-1/separate"])
+}
+
+func TestGraphRunnerMigrateStateRemovesTempDir(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	g := newStateTestGraph(t)
+	ctx := terraform.WithExecutor(context.Background(), &terraform.RecordingExecutor{
+		Respond: func(c terraform.RecordedCommand) (string, error) {
+			if len(c.Args) > 1 && c.Args[0] == "state" && c.Args[1] == "list" {
+				return "", errors.New("state list failed")
+			}
+			return "", nil
+		},
+	})
+
+	h := &hash.MemoryMap{Layout: hash.Layout{"site-1/separate": config.DeploymentSite}}
+	r := &GraphRunner{hash: h}
+
+	err := r.MigrateState(ctx, g, &MigrateStateOptions{})
+	assert.ErrorContains(t, err, "state list failed")
+
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestGraphRunnerApplyLayoutChanges(t *testing.T) {
+	g := newStateTestGraph(t)
+	h := &hash.MemoryMap{Layout: hash.Layout{"site-1/separate": config.DeploymentSite}}
+	r := &GraphRunner{workers: 1, hash: h, batch: batcher.NaiveBatchFunc()}
+
+	recorder := &terraform.RecordingExecutor{}
+	ctx := terraform.WithExecutor(context.Background(), recorder)
+
+	err := r.TerraformApply(ctx, g, &ApplyOptions{AutoApprove: true})
+	var layoutErr *LayoutChangesError
+	require.ErrorAs(t, err, &layoutErr)
+	assert.Equal(t, []LayoutChange{
+		{Site: "site-1", Component: "separate", From: config.DeploymentSite, To: config.DeploymentSiteComponent},
+	}, layoutErr.Changes)
+	assert.ErrorContains(t, err, "site-1/separate from site to site-component")
+	assert.Empty(t, recorder.Commands())
+
+	// When allowed, the changes are only warned about
+	require.NoError(t, r.CheckLayoutChanges(ctx, g, true))
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// stateLock is a state lock that might not have been released by a terraform command
+type stateLock struct {
+	// ID is the lock ID reported by terraform. It is empty if terraform did not report it
+	ID string
+}
+
+// heldLock returns the state lock that might still be held after running a command on a node, based on the error and
+// the error output of the command. This is the case if terraform was killed because it did not exit within the grace
+// period, or if it reported that releasing the lock failed.
+func heldLock(err error, output string) *stateLock {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, utils.ErrProcessKilled) || terraform.LockReleaseFailed(output) {
+		return &stateLock{ID: terraform.ParseLockID(output)}
+	}
+	return nil
+}
+
+// forceUnlockCommand returns the command to release the lock with the given ID of the node
+func forceUnlockCommand(path, id string) string {
+	return fmt.Sprintf("%s -chdir=%s force-unlock %s", utils.TerraformBinary(), path, id)
+}
+
+// warnLocks reports the nodes that might still hold a state lock, with the commands to release them
+func (r *runReport) warnLocks() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var locked []*reportEntry
+	for _, p := range r.paths() {
+		if e := r.entries[p]; e.lock != nil {
+			locked = append(locked, e)
+		}
+	}
+	if len(locked) == 0 {
+		return
+	}
+
+	log.Warn().Msgf("%d node(s) might still hold a state lock. Once no other run is using the state, release the "+
+		"locks:", len(locked))
+	for _, e := range locked {
+		// Terraform only reports the ID of a lock it failed to acquire or release, not of a lock it was killed holding
+		if e.lock.ID == "" {
+			log.Warn().Msgf("%s: the lock ID is unknown, as terraform did not report it. Any terraform command that "+
+				"locks the state in %s reports the ID of the lock that blocks it, which can then be released with "+
+				"force-unlock", r.g.RelativePath(e.node), e.node.Path())
+			continue
+		}
+		log.Warn().Msgf("%s: %s", r.g.RelativePath(e.node), forceUnlockCommand(e.node.Path(), e.lock.ID))
+	}
+}
@@ -0,0 +1,29 @@
+package runner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeldLock(t *testing.T) {
+	assert.Nil(t, heldLock(nil, ""))
+	assert.Nil(t, heldLock(assert.AnError, "Error: Error acquiring the state lock"))
+
+	l := heldLock(fmt.Errorf("command was stopped: %w", utils.ErrProcessKilled), "")
+	if assert.NotNil(t, l) {
+		assert.Equal(t, "", l.ID)
+	}
+
+	l = heldLock(assert.AnError, "Error releasing the state lock\n\nLock Info:\n  ID:        f5b0c7a2\n  Path: state\n")
+	if assert.NotNil(t, l) {
+		assert.Equal(t, "f5b0c7a2", l.ID)
+	}
+}
+
+func TestForceUnlockCommand(t *testing.T) {
+	assert.Equal(t, "terraform -chdir=deployments/main/site-1 force-unlock f5b0c7a2",
+		forceUnlockCommand("deployments/main/site-1", "f5b0c7a2"))
+}
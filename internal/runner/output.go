@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// OutputMode determines how the output of the commands run on the nodes is written
+type OutputMode string
+
+const (
+	// InteractiveOutput connects the commands directly to the terminal. This is only readable with a single worker
+	InteractiveOutput OutputMode = "interactive"
+	// PrefixedOutput prefixes every line with the node it belongs to, for example `[site/component]`
+	PrefixedOutput OutputMode = "prefixed"
+	// GroupedOutput buffers the output of a node and writes it at once when the node has finished
+	GroupedOutput OutputMode = "grouped"
+)
+
+// logsDir is the directory within the node directory in which the logs of the commands are written
+const logsDir = "logs"
+
+// ParseOutputMode parses the output mode. If no mode is given the output is interactive when running with a single
+// worker or when the commands need input, and prefixed otherwise. Input is only available in interactive mode, so the
+// other modes are rejected when the commands need input.
+func ParseOutputMode(value string, workers int, input bool) (OutputMode, error) {
+	switch OutputMode(value) {
+	case "":
+		if workers > 1 && !input {
+			return PrefixedOutput, nil
+		}
+		return InteractiveOutput, nil
+	case InteractiveOutput:
+		return InteractiveOutput, nil
+	case PrefixedOutput, GroupedOutput:
+		if input {
+			return "", fmt.Errorf("terraform asks for input, which is not possible with the %s output mode. "+
+				"Use --output-mode %s or --auto-approve", value, InteractiveOutput)
+		}
+		return OutputMode(value), nil
+	default:
+		return "", fmt.Errorf("unknown output mode %s (expected %s, %s or %s)", value, InteractiveOutput,
+			PrefixedOutput, GroupedOutput)
+	}
+}
+
+// outputLock serializes the output of the nodes that run in parallel
+var outputLock sync.Mutex
+
+// lockedBuffer is a buffer that is safe for concurrent use, as the output and error streams of a command are
+// written concurrently
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// execute runs the executor on the node, with the output of its commands written according to the output mode. The
+// full output of every command is also written to the logs directory of the node. Input is only available in
+// interactive mode. If a capture writer is given, the error output is copied to it as well.
+func (gr *GraphRunner) execute(ctx context.Context, g *graph.Graph, f executorFunc, n graph.Node, capture io.Writer) (string, error) {
+	output := &utils.CommandOutput{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		LogDir: filepath.Join(n.Path(), logsDir),
+	}
+
+	switch gr.outputMode {
+	case PrefixedOutput:
+		prefix := fmt.Sprintf("[%s] ", g.RelativePath(n))
+		stdout := cli.NewPrefixWriter(os.Stdout, prefix, &outputLock)
+		stderr := cli.NewPrefixWriter(os.Stderr, prefix, &outputLock)
+		defer func() {
+			_ = stdout.Flush()
+			_ = stderr.Flush()
+		}()
+		output.Stdin, output.Stdout, output.Stderr = nil, stdout, stderr
+
+	case GroupedOutput:
+		buf := &lockedBuffer{}
+		defer func() {
+			outputLock.Lock()
+			defer outputLock.Unlock()
+
+			if buf.buf.Len() > 0 {
+				log.Info().Msgf("Output of %s", g.RelativePath(n))
+				_, _ = buf.buf.WriteTo(os.Stdout)
+			}
+		}()
+		output.Stdin, output.Stdout, output.Stderr = nil, buf, buf
+	}
+
+	if capture != nil {
+		output.Stderr = io.MultiWriter(output.Stderr, capture)
+	}
+
+	ctx = utils.WithCommandTimeout(utils.WithCommandOutput(ctx, output), gr.commandTimeout(n))
+	return f(ctx, n)
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// outputCache contains the terraform outputs of the nodes that were read during a run, so the outputs of a parent are
+// read once instead of once for every child. It is safe for concurrent use.
+type outputCache struct {
+	mu      sync.Mutex
+	entries map[string]*outputCacheEntry
+}
+
+type outputCacheEntry struct {
+	// mu is held while the outputs are read, so concurrent readers of the same node wait for the result
+	mu     sync.Mutex
+	loaded bool
+	value  cty.Value
+	err    error
+}
+
+type outputCacheKey struct{}
+
+// withOutputCache returns a context with an empty output cache, unless the context already has one. The cache is
+// scoped to a single run, as the outputs can be changed by other processes between runs.
+func withOutputCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(outputCacheKey{}).(*outputCache); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, outputCacheKey{}, &outputCache{entries: map[string]*outputCacheEntry{}})
+}
+
+func (c *outputCache) entry(path string) *outputCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[path]
+	if !ok {
+		e = &outputCacheEntry{}
+		c.entries[path] = e
+	}
+	return e
+}
+
+// cachedOutput returns the terraform outputs of the node at the given path. They are read at most once per run,
+// unless the node is applied in between. Failures are cached as well, except when the context is done, as another
+// node might still be able to read the outputs.
+func cachedOutput(ctx context.Context, path string) (cty.Value, error) {
+	c, ok := ctx.Value(outputCacheKey{}).(*outputCache)
+	if !ok {
+		return terraform.Output(ctx, path)
+	}
+
+	e := c.entry(path)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.loaded {
+		return e.value, e.err
+	}
+
+	v, err := terraform.Output(ctx, path)
+	if err != nil && ctx.Err() != nil {
+		return v, err
+	}
+	e.loaded, e.value, e.err = true, v, err
+	return v, err
+}
+
+// invalidateOutput removes the outputs of the node at the given path from the cache, so they are read again after the
+// node has changed its state
+func invalidateOutput(ctx context.Context, path string) {
+	c, ok := ctx.Value(outputCacheKey{}).(*outputCache)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, path)
+}
@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countOutputs returns the number of times the outputs of the given path were read
+func countOutputs(recorder *terraform.RecordingExecutor, path string) int {
+	count := 0
+	for _, c := range recorder.Commands() {
+		if c.Path == path && c.Args[0] == "output" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestCachedOutput(t *testing.T) {
+	recorder := &terraform.RecordingExecutor{
+		Respond: func(c terraform.RecordedCommand) (string, error) {
+			if c.Path == "missing" {
+				return "", errors.New("no state")
+			}
+			return `{"component": {"value": {"url": "https://example.com"}}}`, nil
+		},
+	}
+	ctx := withOutputCache(terraform.WithExecutor(context.Background(), recorder))
+
+	for i := 0; i < 2; i++ {
+		v, err := cachedOutput(ctx, "site")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", v.GetAttr("component").GetAttr("value").GetAttr("url").AsString())
+
+		_, err = cachedOutput(ctx, "missing")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 1, countOutputs(recorder, "site"))
+	assert.Equal(t, 1, countOutputs(recorder, "missing"))
+
+	// The outputs are read again once the node is applied
+	invalidateOutput(ctx, "site")
+	_, err := cachedOutput(ctx, "site")
+	require.NoError(t, err)
+	assert.Equal(t, 2, countOutputs(recorder, "site"))
+
+	// Every run has its own cache
+	_, err = cachedOutput(withOutputCache(terraform.WithExecutor(context.Background(), recorder)), "site")
+	require.NoError(t, err)
+	assert.Equal(t, 3, countOutputs(recorder, "site"))
+}
+
+func TestCachedOutputCancelled(t *testing.T) {
+	recorder := &terraform.RecordingExecutor{
+		Respond: func(terraform.RecordedCommand) (string, error) {
+			return "", context.Canceled
+		},
+	}
+	ctx := withOutputCache(terraform.WithExecutor(context.Background(), recorder))
+
+	// A failure caused by cancelling one node is not cached for the other nodes in the run
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err := cachedOutput(cancelled, "site")
+	assert.Error(t, err)
+
+	recorder.Respond = func(terraform.RecordedCommand) (string, error) { return "{}", nil }
+	_, err = cachedOutput(ctx, "site")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, countOutputs(recorder, "site"))
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutputMode(t *testing.T) {
+	mode, err := ParseOutputMode("", 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, InteractiveOutput, mode)
+
+	mode, err = ParseOutputMode("", 4, false)
+	require.NoError(t, err)
+	assert.Equal(t, PrefixedOutput, mode)
+
+	mode, err = ParseOutputMode("grouped", 4, false)
+	require.NoError(t, err)
+	assert.Equal(t, GroupedOutput, mode)
+
+	_, err = ParseOutputMode("unknown", 1, false)
+	assert.Error(t, err)
+
+	// Commands that need input are run interactively by default, and cannot be run in the other modes
+	mode, err = ParseOutputMode("", 4, true)
+	require.NoError(t, err)
+	assert.Equal(t, InteractiveOutput, mode)
+
+	mode, err = ParseOutputMode("interactive", 4, true)
+	require.NoError(t, err)
+	assert.Equal(t, InteractiveOutput, mode)
+
+	_, err = ParseOutputMode("prefixed", 4, true)
+	assert.Error(t, err)
+
+	_, err = ParseOutputMode("grouped", 1, true)
+	assert.Error(t, err)
+}
+
+func TestExecuteWritesLogs(t *testing.T) {
+	for _, mode := range []OutputMode{InteractiveOutput, PrefixedOutput, GroupedOutput} {
+		t.Run(string(mode), func(t *testing.T) {
+			dir := t.TempDir()
+			start := new(graph.NodeMock)
+			start.On("Path").Return(filepath.Dir(dir))
+			n := new(graph.NodeMock)
+			n.On("Path").Return(dir)
+			g := &graph.Graph{StartNode: start}
+
+			gr := &GraphRunner{outputMode: mode}
+			_, err := gr.execute(context.Background(), g, func(ctx context.Context, n graph.Node) (string, error) {
+				return utils.RunInteractive(ctx, false, "sh", n.Path(), "-c", "echo out; echo err >&2")
+			}, n, nil)
+			require.NoError(t, err)
+
+			logs, err := filepath.Glob(filepath.Join(dir, logsDir, "-c-*.log"))
+			require.NoError(t, err)
+			require.Len(t, logs, 1)
+
+			content, err := os.ReadFile(logs[0])
+			require.NoError(t, err)
+			assert.Contains(t, string(content), "out\n")
+			assert.Contains(t, string(content), "err\n")
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config/variable"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+type (
+	// outputsFetcher returns the terraform outputs of the node at the given path
+	outputsFetcher func(ctx context.Context, path string) (cty.Value, error)
+
+	// outputsDigestFunc sets the digest of the referenced upstream outputs on a node before its hash is compared
+	outputsDigestFunc func(ctx context.Context, g *graph.Graph, n graph.Node) error
+)
+
+// errOutputsUnavailable is returned if the outputs of a referenced node cannot be read
+var errOutputsUnavailable = errors.New("referenced outputs are not available")
+
+// terraformOutputsDigest reads the referenced outputs from the terraform state of the upstream nodes, using the output
+// cache of the run. If they cannot be read, for example because the upstream nodes are not initialized on a clean
+// checkout, the digest that was stored with the previous hash is used instead, so the node is only tainted by outputs
+// that were actually read.
+func terraformOutputsDigest(hashFetcher hash.Handler) outputsDigestFunc {
+	stored := storedOutputsDigest(hashFetcher)
+	return func(ctx context.Context, g *graph.Graph, n graph.Node) error {
+		err := setOutputsDigest(ctx, g, n, initializedOutput)
+		if errors.Is(err, errOutputsUnavailable) {
+			log.Debug().Err(err).Msgf("Using the stored outputs digest for %s", n.Identifier())
+			return stored(ctx, g, n)
+		}
+		return err
+	}
+}
+
+// initializedOutput returns the outputs of the node at the given path if it is initialized. Reading the outputs of
+// an uninitialized node would fail anyway.
+func initializedOutput(ctx context.Context, path string) (cty.Value, error) {
+	if !terraformIsInitialized(path) {
+		return cty.NilVal, fmt.Errorf("%s is not initialized", path)
+	}
+	return cachedOutput(ctx, path)
+}
+
+// storedOutputsDigest uses the digest that was stored together with the previous hash of the node. This assumes the
+// referenced outputs did not change, but does not require access to the terraform state.
+func storedOutputsDigest(hashFetcher hash.Handler) outputsDigestFunc {
+	return func(ctx context.Context, _ *graph.Graph, n graph.Node) error {
+		sc, ok := n.(*graph.SiteComponent)
+		if !ok || len(sc.ReferencedOutputs()) == 0 {
+			return nil
+		}
+
+		digest, err := hashFetcher.FetchOutputsDigest(ctx, n)
+		if err != nil {
+			return err
+		}
+		sc.SetOutputsDigest(digest)
+		return nil
+	}
+}
+
+// setOutputsDigest computes the digest of the upstream outputs referenced by a site component and sets it on the node,
+// so that changes to these outputs made outside mach-composer will taint the node. Other node types are ignored. If any
+// of the outputs cannot be read errOutputsUnavailable is returned, and the digest is left unchanged.
+func setOutputsDigest(ctx context.Context, g *graph.Graph, n graph.Node, fetch outputsFetcher) error {
+	sc, ok := n.(*graph.SiteComponent)
+	if !ok {
+		return nil
+	}
+
+	refs := sc.ReferencedOutputs()
+	if len(refs) == 0 {
+		return nil
+	}
+
+	outputs := map[string]cty.Value{}
+	for _, ref := range refs {
+		p := referencedNodePath(g, sc, ref)
+		if _, ok := outputs[p]; ok {
+			continue
+		}
+
+		v, err := fetch(ctx, p)
+		if err != nil {
+			return fmt.Errorf("%w: unable to read outputs of %s for %s: %w", errOutputsUnavailable, p, sc.Path(), err)
+		}
+		outputs[p] = v
+	}
+
+	digest, err := digestOutputs(refs, func(ref variable.OutputReference) cty.Value {
+		return outputs[referencedNodePath(g, sc, ref)]
+	})
+	if err != nil {
+		return err
+	}
+
+	sc.SetOutputsDigest(digest)
+	return nil
+}
+
+// referencedNodePath returns the path of the node that holds the state of the referenced component. This is either
+// the component itself when it is deployed separately, or the site it is deployed in.
+func referencedNodePath(g *graph.Graph, sc *graph.SiteComponent, ref variable.OutputReference) string {
+	sitePath := sc.Ancestor().Path()
+	componentPath := path.Join(sitePath, ref.Component)
+	if _, err := g.Vertex(componentPath); err == nil {
+		return componentPath
+	}
+	return sitePath
+}
+
+// digestOutputs computes a digest of the referenced output values. Every component output is exposed as an object
+// containing the module outputs, so the value is looked up as <component>.value.<output>. Outputs that cannot be
+// found are included as null.
+func digestOutputs(refs []variable.OutputReference, outputs func(ref variable.OutputReference) cty.Value) (string, error) {
+	values := make(map[string]json.RawMessage, len(refs))
+	for _, ref := range refs {
+		v := lookupOutput(outputs(ref), append([]string{ref.Component, "value"}, strings.Split(ref.Output, ".")...))
+		if v == cty.NilVal || v.IsNull() || !v.IsWhollyKnown() {
+			values[ref.String()] = nil
+			continue
+		}
+
+		data, err := ctyjson.SimpleJSONValue{Value: v}.MarshalJSON()
+		if err != nil {
+			return "", err
+		}
+		values[ref.String()] = data
+	}
+
+	return utils.ComputeHash(values)
+}
+
+func lookupOutput(v cty.Value, names []string) cty.Value {
+	for _, name := range names {
+		if v == cty.NilVal || v.IsNull() || !v.IsKnown() {
+			return cty.NilVal
+		}
+
+		switch {
+		case v.Type().IsObjectType() && v.Type().HasAttribute(name):
+			v = v.GetAttr(name)
+		case v.Type().IsMapType() && v.HasIndex(cty.StringVal(name)).True():
+			v = v.Index(cty.StringVal(name))
+		default:
+			return cty.NilVal
+		}
+	}
+	return v
+}
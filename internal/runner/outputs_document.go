@@ -0,0 +1,200 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/rs/zerolog/log"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedValue replaces the values of sensitive outputs
+const RedactedValue = "(sensitive)"
+
+// OutputsDocument contains the terraform outputs of the components, keyed by `<site>/<component>` and the output name
+type OutputsDocument map[string]map[string]any
+
+// TerraformOutputs collects the outputs of every node. Every component exposes its module outputs through a single
+// terraform output named after the component, which is unpacked into the individual outputs. Terraform is not
+// initialized, as its output would end up in the document, and the report of the run is written to stderr for the same
+// reason.
+func (gr *GraphRunner) TerraformOutputs(ctx context.Context, dg *graph.Graph, opts *OutputsOptions) (OutputsDocument, error) {
+	var mu sync.Mutex
+	doc := OutputsDocument{}
+
+	if err := gr.schedule(ctx, dg, func(ctx context.Context, n graph.Node) (string, error) {
+		if !terraformIsInitialized(n.Path()) {
+			return "", fmt.Errorf("terraform is not initialized for %s. Please run init beforehand", n.Path())
+		}
+
+		v, err := cachedOutput(ctx, n.Path())
+		if err != nil {
+			return "", err
+		}
+
+		site := dg.RelativePath(n)
+		if n.Type() != graph.SiteType {
+			site = path.Dir(site)
+		}
+
+		outputs, err := unpackOutputs(site, v, func(component string) (map[string]bool, error) {
+			return terraform.SensitiveModuleOutputs(n.Path(), component)
+		}, opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to read outputs of %s: %w", n.Path(), err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for k, o := range outputs {
+			doc[k] = o
+		}
+		return "", nil
+	}, &runOptions{IgnoreChangeDetection: true, ReportWriter: os.Stderr}); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// sensitiveOutputsFunc returns the names of the sensitive outputs of the module of a component
+type sensitiveOutputsFunc func(component string) (map[string]bool, error)
+
+// unpackOutputs converts the outputs of the terraform state of a node, as returned by terraform output -json, to the
+// outputs of the components deployed in it. The component output is always sensitive, as it contains all module
+// outputs, so the sensitivity of the individual outputs is read from the module. If that fails, all outputs of the
+// component are redacted. Nothing is redacted if ShowSensitive is set.
+func unpackOutputs(site string, v cty.Value, sensitiveOutputs sensitiveOutputsFunc, opts *OutputsOptions) (OutputsDocument, error) {
+	doc := OutputsDocument{}
+	if v.IsNull() || !v.Type().IsObjectType() {
+		return doc, nil
+	}
+
+	for component, output := range v.AsValueMap() {
+		if len(opts.Components) > 0 && !slices.Contains(opts.Components, component) {
+			continue
+		}
+
+		redact := func(string) bool { return false }
+		sensitive := lookupOutput(output, []string{"sensitive"})
+		if sensitive != cty.NilVal && sensitive.Type() == cty.Bool && sensitive.True() && !opts.ShowSensitive {
+			names, err := sensitiveOutputs(component)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Unable to determine the sensitive outputs of %s, redacting all outputs", component)
+				redact = func(string) bool { return true }
+			} else {
+				redact = func(name string) bool { return names[name] }
+			}
+		}
+
+		outputs := map[string]any{}
+		value := lookupOutput(output, []string{"value"})
+		if value != cty.NilVal && !value.IsNull() && (value.Type().IsObjectType() || value.Type().IsMapType()) {
+			for name, o := range value.AsValueMap() {
+				if redact(name) {
+					outputs[name] = RedactedValue
+					continue
+				}
+
+				converted, err := convertOutput(o)
+				if err != nil {
+					return nil, err
+				}
+				outputs[name] = converted
+			}
+		}
+
+		doc[path.Join(site, component)] = outputs
+	}
+
+	return doc, nil
+}
+
+func convertOutput(v cty.Value) (any, error) {
+	if v.IsNull() || !v.IsWhollyKnown() {
+		return nil, nil
+	}
+
+	data, err := ctyjson.SimpleJSONValue{Value: v}.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var result any
+	if err = json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// WriteJSON renders the document as an indented JSON document
+func (d OutputsDocument) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// WriteYAML renders the document as a YAML document
+func (d OutputsDocument) WriteYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(d); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+var dotenvKeyInvalidChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// WriteDotenv renders the document as environment variables named `<SITE>_<COMPONENT>_<OUTPUT>`, ordered by name.
+// Values that are not strings are JSON encoded.
+func (d OutputsDocument) WriteDotenv(w io.Writer) error {
+	lines := map[string]string{}
+	for component, outputs := range d {
+		for name, value := range outputs {
+			key := dotenvKeyInvalidChars.ReplaceAllString(strings.ToUpper(component+"_"+name), "_")
+
+			s, ok := value.(string)
+			if !ok {
+				data, err := json.Marshal(value)
+				if err != nil {
+					return err
+				}
+				s = string(data)
+			}
+			lines[key] = s
+		}
+	}
+
+	keys := make([]string, 0, len(lines))
+	for k := range lines {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", k, quoteDotenv(lines[k])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quoteDotenv quotes a value in double quotes, escaping the characters that are interpreted within them
+func quoteDotenv(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`)
+	return `"` + r.Replace(s) + `"`
+}
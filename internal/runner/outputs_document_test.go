@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func testStateOutputs() cty.Value {
+	return cty.ObjectVal(map[string]cty.Value{
+		"payment": cty.ObjectVal(map[string]cty.Value{
+			"sensitive": cty.True,
+			"value": cty.ObjectVal(map[string]cty.Value{
+				"endpoint": cty.StringVal("https://payment.example.com"),
+				"ports":    cty.ListVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(443)}),
+			}),
+		}),
+		"search": cty.ObjectVal(map[string]cty.Value{
+			"sensitive": cty.False,
+			"value": cty.ObjectVal(map[string]cty.Value{
+				"index": cty.StringVal("products"),
+			}),
+		}),
+	})
+}
+
+func TestUnpackOutputs(t *testing.T) {
+	sensitiveOutputs := func(component string) (map[string]bool, error) {
+		if component == "payment" {
+			return map[string]bool{"endpoint": true}, nil
+		}
+		return nil, errors.New("module not installed")
+	}
+
+	doc, err := unpackOutputs("site-1", testStateOutputs(), sensitiveOutputs, &OutputsOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, OutputsDocument{
+		"site-1/payment": {"endpoint": RedactedValue, "ports": []any{float64(80), float64(443)}},
+		"site-1/search":  {"index": "products"},
+	}, doc)
+
+	doc, err = unpackOutputs("site-1", testStateOutputs(), sensitiveOutputs, &OutputsOptions{
+		ShowSensitive: true,
+		Components:    []string{"payment"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, OutputsDocument{
+		"site-1/payment": {"endpoint": "https://payment.example.com", "ports": []any{float64(80), float64(443)}},
+	}, doc)
+
+	doc, err = unpackOutputs("site-1", cty.EmptyObjectVal, sensitiveOutputs, &OutputsOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, doc)
+}
+
+func TestUnpackOutputsUnknownSensitivity(t *testing.T) {
+	doc, err := unpackOutputs("site-1", testStateOutputs(), func(string) (map[string]bool, error) {
+		return nil, errors.New("module not installed")
+	}, &OutputsOptions{Components: []string{"payment"}})
+	require.NoError(t, err)
+	assert.Equal(t, OutputsDocument{
+		"site-1/payment": {"endpoint": RedactedValue, "ports": RedactedValue},
+	}, doc)
+}
+
+func TestOutputsDocumentFormats(t *testing.T) {
+	doc := OutputsDocument{
+		"site-1/payment": {"endpoint": "https://payment.example.com", "ports": []any{float64(80), float64(443)}},
+		"site-1/search":  {"query": "say \"$HOME\"\n"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, doc.WriteDotenv(&buf))
+	assert.Equal(t, `SITE_1_PAYMENT_ENDPOINT="https://payment.example.com"
+SITE_1_PAYMENT_PORTS="[80,443]"
+SITE_1_SEARCH_QUERY="say \"\$HOME\"\n"
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, doc.WriteYAML(&buf))
+	assert.Equal(t, `site-1/payment:
+  endpoint: https://payment.example.com
+  ports:
+    - 80
+    - 443
+site-1/search:
+  query: |
+    say "$HOME"
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, doc.WriteJSON(&buf))
+	assert.JSONEq(t, `{
+		"site-1/payment": {"endpoint": "https://payment.example.com", "ports": [80, 443]},
+		"site-1/search": {"query": "say \"$HOME\"\n"}
+	}`, buf.String())
+}
@@ -0,0 +1,151 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/config/variable"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func componentOutputs(component string, outputs map[string]cty.Value) cty.Value {
+	return cty.ObjectVal(map[string]cty.Value{
+		component: cty.ObjectVal(map[string]cty.Value{
+			"sensitive": cty.True,
+			"value":     cty.ObjectVal(outputs),
+		}),
+	})
+}
+
+// newOutputsTestGraph creates a graph in the given directory with a component that references outputs of a separately
+// deployed component and of a component deployed in the site
+func newOutputsTestGraph(t *testing.T, dir string) (*graph.Graph, *graph.SiteComponent) {
+	endpoint, err := variable.NewScalarVariable("${component.upstream.endpoint}")
+	require.NoError(t, err)
+	nested, err := variable.NewScalarVariable("${component.nested.url}")
+	require.NoError(t, err)
+
+	upstream := gitComponent("upstream")
+	upstream.Deployment = &config.Deployment{Type: config.DeploymentSiteComponent}
+
+	g := newTestGraph(t, dir, config.DeploymentSite, gitComponent("nested"), upstream,
+		config.SiteComponentConfig{
+			Name:       "dependent",
+			Deployment: upstream.Deployment,
+			Variables:  variable.VariablesMap{"endpoint": endpoint},
+			Secrets:    variable.VariablesMap{"url": nested},
+			Definition: &config.ComponentConfig{
+				Name:   "dependent",
+				Source: "testdata/empty",
+			},
+		},
+	)
+
+	n, err := g.Vertex(filepath.Join(dir, "main", "site-1", "dependent"))
+	require.NoError(t, err)
+	return g, n.(*graph.SiteComponent)
+}
+
+func TestSetOutputsDigest(t *testing.T) {
+	g, dependent := newOutputsTestGraph(t, "")
+
+	outputs := map[string]cty.Value{
+		"main/site-1/upstream": componentOutputs("upstream", map[string]cty.Value{
+			"endpoint": cty.StringVal("https://a.example.com"),
+			"unused":   cty.StringVal("foo"),
+		}),
+		"main/site-1": componentOutputs("nested", map[string]cty.Value{
+			"url": cty.StringVal("https://b.example.com"),
+		}),
+	}
+	var fetched []string
+	fetch := func(ctx context.Context, path string) (cty.Value, error) {
+		fetched = append(fetched, path)
+		return outputs[path], nil
+	}
+
+	hashFor := func() string {
+		require.NoError(t, setOutputsDigest(context.Background(), g, dependent, fetch))
+		h, err := dependent.Hash()
+		require.NoError(t, err)
+		return h
+	}
+
+	original := hashFor()
+	assert.ElementsMatch(t, []string{"main/site-1", "main/site-1/upstream"}, fetched)
+
+	// Changing an output that is not referenced does not change the hash
+	outputs["main/site-1/upstream"] = componentOutputs("upstream", map[string]cty.Value{
+		"endpoint": cty.StringVal("https://a.example.com"),
+		"unused":   cty.StringVal("bar"),
+	})
+	assert.Equal(t, original, hashFor())
+
+	// Changing a referenced output of a separately deployed component changes the hash
+	outputs["main/site-1/upstream"] = componentOutputs("upstream", map[string]cty.Value{
+		"endpoint": cty.StringVal("https://c.example.com"),
+	})
+	changed := hashFor()
+	assert.NotEqual(t, original, changed)
+
+	// Changing a referenced output of a component deployed in the site changes the hash
+	outputs["main/site-1"] = componentOutputs("nested", map[string]cty.Value{
+		"url": cty.StringVal("https://d.example.com"),
+	})
+	assert.NotEqual(t, changed, hashFor())
+}
+
+func TestSetOutputsDigestUnavailableOutputs(t *testing.T) {
+	g, dependent := newOutputsTestGraph(t, "")
+	dependent.SetOutputsDigest("previous")
+
+	err := setOutputsDigest(context.Background(), g, dependent, func(ctx context.Context, path string) (cty.Value, error) {
+		return cty.NilVal, errors.New("no state")
+	})
+	assert.ErrorIs(t, err, errOutputsUnavailable)
+	assert.Equal(t, "previous", dependent.OutputsDigest())
+}
+
+func TestTerraformOutputsDigestUninitialized(t *testing.T) {
+	g, dependent := newOutputsTestGraph(t, "")
+
+	h := hash.NewJsonFileHandler(filepath.Join(t.TempDir(), "hashes.json"))
+	dependent.SetOutputsDigest("stored")
+	require.NoError(t, h.Store(context.Background(), dependent))
+	dependent.SetOutputsDigest("")
+
+	// The upstream nodes are not initialized, so the stored digest is used instead of reading their outputs
+	recorder := &terraform.RecordingExecutor{}
+	ctx := terraform.WithExecutor(context.Background(), recorder)
+	require.NoError(t, terraformOutputsDigest(h)(ctx, g, dependent))
+	assert.Equal(t, "stored", dependent.OutputsDigest())
+	assert.Empty(t, recorder.Commands())
+}
+
+func TestGraphRunnerStoredOutputsDigest(t *testing.T) {
+	dir := t.TempDir()
+	g, _ := newOutputsTestGraph(t, dir)
+	initializeNodes(t, filepath.Join(dir, "main/site-1"), filepath.Join(dir, "main/site-1/upstream"))
+
+	r := &GraphRunner{workers: 1, hash: hash.NewMemoryMapHandler(), batch: batcher.NaiveBatchFunc()}
+	noop := func(ctx context.Context, n graph.Node) (string, error) { return "", nil }
+
+	// The outputs of the initialized parents are read to detect changes made outside mach-composer
+	recorder := &terraform.RecordingExecutor{}
+	require.NoError(t, r.run(terraform.WithExecutor(context.Background(), recorder), g, noop, &runOptions{}))
+	assert.NotEmpty(t, recorder.Commands())
+
+	recorder = &terraform.RecordingExecutor{}
+	require.NoError(t, r.run(terraform.WithExecutor(context.Background(), recorder), g, noop,
+		&runOptions{StoredOutputsDigest: true}))
+	assert.Empty(t, recorder.Commands())
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/rs/zerolog/log"
+)
+
+// storePlanMetadata records the configuration the plan of the node was made for, so apply can detect stale plans
+func storePlanMetadata(n graph.Node) error {
+	h, err := n.Hash()
+	if err != nil {
+		return err
+	}
+
+	m, err := terraform.NewPlanMetadata(n.Path(), h)
+	if err != nil {
+		return err
+	}
+
+	return terraform.WritePlanMetadata(n.Path(), m)
+}
+
+// checkPlan verifies that the saved plan of the node, if any, was made for the current configuration. A plan without
+// sidecar is considered stale as well, because it cannot be verified. Stale plans are removed if discardStale is set,
+// so terraform plans again during apply, otherwise an error is returned.
+func checkPlan(n graph.Node, discardStale bool) error {
+	ok, err := terraform.HasPlan(n.Path())
+	if err != nil || !ok {
+		return err
+	}
+
+	stale, err := isStalePlan(n)
+	if err != nil {
+		return err
+	}
+	if !stale {
+		return nil
+	}
+
+	if !discardStale {
+		return fmt.Errorf("the saved plan for %s does not match the current configuration, run plan again "+
+			"or use --replan-stale to discard it", n.Path())
+	}
+
+	log.Warn().Msgf("Discarding saved plan for %s because it does not match the current configuration", n.Path())
+	return terraform.RemovePlan(n.Path())
+}
+
+func isStalePlan(n graph.Node) (bool, error) {
+	saved, err := terraform.ReadPlanMetadata(n.Path())
+	if err != nil || saved == nil {
+		return true, err
+	}
+
+	h, err := n.Hash()
+	if err != nil {
+		return false, err
+	}
+
+	current, err := terraform.NewPlanMetadata(n.Path(), h)
+	if err != nil {
+		return false, err
+	}
+
+	return *saved != *current, nil
+}
+
+// replan replaces the saved plan of the node, if any, by a new plan. This is needed before retrying a failed apply, as
+// the failed attempt might have changed part of the state, which makes the saved plan stale.
+func replan(ctx context.Context, n graph.Node) (string, error) {
+	ok, err := terraform.HasPlan(n.Path())
+	if err != nil || !ok {
+		return "", err
+	}
+
+	log.Warn().Msgf("Planning %s again, as the saved plan might be stale after the failed attempt", n.Identifier())
+	out, err := terraform.Plan(ctx, n.Path(), true)
+	if err != nil {
+		return out, err
+	}
+	return out, storePlanMetadata(n)
+}
@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/rs/zerolog/log"
+)
+
+// PlanReview is the combined summary of the saved plans of the nodes that will be applied. It is safe for concurrent
+// use.
+type PlanReview struct {
+	mu sync.Mutex
+	// Plans contains the summary of every saved plan, keyed by the node path relative to the project
+	Plans map[string]*terraform.PlanSummary
+	// Unplanned contains the nodes that should be applied but have no saved plan, for example because the outputs of
+	// their dependencies are not available yet
+	Unplanned []string
+}
+
+func (r *PlanReview) addPlan(path string, s *terraform.PlanSummary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Plans[path] = s
+}
+
+func (r *PlanReview) addUnplanned(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Unplanned = append(r.Unplanned, path)
+}
+
+// HasChanges returns true if any of the plans contains resource changes
+func (r *PlanReview) HasChanges() bool {
+	for _, s := range r.Plans {
+		if s.HasChanges() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasDestroys returns true if any of the plans deletes or replaces resources
+func (r *PlanReview) HasDestroys() bool {
+	for _, s := range r.Plans {
+		if s.Delete.Count+s.Replace.Count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders the number of planned changes per node as a table ordered by node path, followed by the totals
+func (r *PlanReview) Write(w io.Writer) {
+	var paths []string
+	for p := range r.Plans {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var data [][]string
+	var add, change, replace, destroy int
+	for _, p := range paths {
+		s := r.Plans[p]
+		data = append(data, []string{p, strconv.Itoa(s.Create.Count), strconv.Itoa(s.Update.Count),
+			strconv.Itoa(s.Replace.Count), strconv.Itoa(s.Delete.Count)})
+		add += s.Create.Count
+		change += s.Update.Count
+		replace += s.Replace.Count
+		destroy += s.Delete.Count
+	}
+
+	cli.WriteTable(w, []string{"Node", "Add", "Change", "Replace", "Destroy"}, data)
+	_, _ = fmt.Fprintf(w, "%d to add, %d to change, %d to replace, %d to destroy\n", add, change, replace, destroy)
+
+	if len(r.Unplanned) > 0 {
+		sort.Strings(r.Unplanned)
+		_, _ = fmt.Fprintln(w, "The following components have no saved plan and will not be applied:")
+		for _, p := range r.Unplanned {
+			_, _ = fmt.Fprintf(w, " - %s\n", p)
+		}
+	}
+}
+
+// planSummary summarizes the saved plan of the node
+func planSummary(ctx context.Context, n graph.Node) (*terraform.PlanSummary, error) {
+	out, err := terraform.ShowJSON(ctx, n.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := terraform.ParsePlanSummary([]byte(out))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize plan of %s: %w", n.Path(), err)
+	}
+	return summary, nil
+}
+
+// ReviewPlans summarizes the saved plans of the nodes that will be applied, so all changes can be approved at once
+// before applying the plans
+func (gr *GraphRunner) ReviewPlans(ctx context.Context, dg *graph.Graph, opts *ReviewOptions) (*PlanReview, error) {
+	review := &PlanReview{Plans: map[string]*terraform.PlanSummary{}}
+
+	if err := gr.run(ctx, dg, func(ctx context.Context, n graph.Node) (string, error) {
+		ok, err := terraform.HasPlan(n.Path())
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			log.Warn().Msgf("No saved plan found for %s", n.Identifier())
+			review.addUnplanned(dg.RelativePath(n))
+			return "", nil
+		}
+
+		summary, err := planSummary(ctx, n)
+		if err != nil {
+			return "", err
+		}
+		review.addPlan(dg.RelativePath(n), summary)
+		return "", nil
+	}, &runOptions{
+		IgnoreChangeDetection: opts.IgnoreChangeDetection,
+		Targets:               opts.Targets,
+	}); err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
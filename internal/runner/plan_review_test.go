@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const destroyingPlanJSON = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_s3_bucket.new", "change": {"actions": ["create"]}},
+    {"address": "aws_iam_role.old", "change": {"actions": ["delete"]}}
+  ]
+}`
+
+// savePlan creates a saved plan for the node that is up-to-date with its generated file
+func savePlan(t *testing.T, n graph.Node) {
+	require.NoError(t, os.MkdirAll(n.Path(), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(n.Path(), "main.tf"), []byte("# generated"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(n.Path(), terraform.PlanFile), []byte("plan"), 0600))
+	require.NoError(t, storePlanMetadata(n))
+}
+
+func TestPlanReview(t *testing.T) {
+	review := &PlanReview{Plans: map[string]*terraform.PlanSummary{}}
+	assert.False(t, review.HasChanges())
+
+	review.addPlan("site-1/component", &terraform.PlanSummary{
+		Create: terraform.ResourceChanges{Count: 2},
+		Update: terraform.ResourceChanges{Count: 1},
+	})
+	review.addPlan("site-1", &terraform.PlanSummary{})
+	review.addUnplanned("site-2/component")
+
+	assert.True(t, review.HasChanges())
+	assert.False(t, review.HasDestroys())
+
+	review.addPlan("site-2", &terraform.PlanSummary{Replace: terraform.ResourceChanges{Count: 1}})
+	assert.True(t, review.HasDestroys())
+
+	buf := &bytes.Buffer{}
+	review.Write(buf)
+	assert.Contains(t, buf.String(), "2 to add, 1 to change, 1 to replace, 0 to destroy")
+	assert.Contains(t, buf.String(), " - site-2/component")
+}
+
+func TestGraphRunnerReviewAndApplyPlans(t *testing.T) {
+	g := newTestGraph(t, t.TempDir(), config.DeploymentSiteComponent, gitComponent("component"))
+
+	n := testNode(t, g, graph.SiteComponentType)
+	savePlan(t, n)
+
+	recorder := &terraform.RecordingExecutor{
+		Respond: func(c terraform.RecordedCommand) (string, error) {
+			if c.Args[0] == "show" {
+				return destroyingPlanJSON, nil
+			}
+			return "", nil
+		},
+	}
+	ctx := terraform.WithExecutor(context.Background(), recorder)
+	r := &GraphRunner{workers: 1, hash: hash.NewMemoryMapHandler(), batch: batcher.NaiveBatchFunc()}
+
+	review, err := r.ReviewPlans(ctx, g, &ReviewOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"site-1"}, review.Unplanned)
+	require.Contains(t, review.Plans, "site-1/component")
+	assert.True(t, review.HasDestroys())
+
+	// Only the node with a saved plan is applied
+	require.NoError(t, r.TerraformApply(ctx, g, &ApplyOptions{AutoApprove: true, RequirePlan: true}))
+
+	var applied []string
+	for _, c := range recorder.Commands() {
+		if c.Args[0] == "apply" {
+			applied = append(applied, c.Path)
+		}
+	}
+	assert.Equal(t, []string{n.Path()}, applied)
+}
+
+func TestSkipStatusRequirePlan(t *testing.T) {
+	g := newTestGraph(t, t.TempDir(), config.DeploymentSiteComponent, gitComponent("component"))
+
+	n := testNode(t, g, graph.SiteComponentType)
+	savePlan(t, n)
+
+	site := testNode(t, g, graph.SiteType)
+
+	opts := &runOptions{IgnoreChangeDetection: true, RequirePlan: true}
+	assert.Equal(t, NodeStatus(""), skipStatus(n, nil, opts))
+	assert.Equal(t, StatusNoPlan, skipStatus(site, nil, opts))
+	assert.Equal(t, NodeStatus(""), skipStatus(site, nil, &runOptions{IgnoreChangeDetection: true}))
+}
@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPlannedNode(t *testing.T, hash string) (*graph.NodeMock, string) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`provider "aws" {}`), 0600))
+
+	n := new(graph.NodeMock)
+	n.On("Path").Return(dir)
+	n.On("Hash").Return(hash, nil)
+	return n, dir
+}
+
+func TestCheckPlanNoPlan(t *testing.T) {
+	n, _ := newPlannedNode(t, "hash")
+	assert.NoError(t, checkPlan(n, false))
+}
+
+func TestCheckPlanMatching(t *testing.T) {
+	n, dir := newPlannedNode(t, "hash")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, terraform.PlanFile), []byte("plan"), 0600))
+	require.NoError(t, storePlanMetadata(n))
+
+	assert.NoError(t, checkPlan(n, false))
+	assert.FileExists(t, filepath.Join(dir, terraform.PlanFile))
+}
+
+func TestCheckPlanStale(t *testing.T) {
+	n, dir := newPlannedNode(t, "hash")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, terraform.PlanFile), []byte("plan"), 0600))
+	require.NoError(t, storePlanMetadata(n))
+
+	// The generated configuration changed after the plan was made
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`provider "google" {}`), 0600))
+
+	assert.ErrorContains(t, checkPlan(n, false), "does not match the current configuration")
+	assert.FileExists(t, filepath.Join(dir, terraform.PlanFile))
+
+	assert.NoError(t, checkPlan(n, true))
+	assert.NoFileExists(t, filepath.Join(dir, terraform.PlanFile))
+	assert.NoFileExists(t, filepath.Join(dir, terraform.PlanMetadataFile))
+}
+
+func TestCheckPlanChangedHash(t *testing.T) {
+	n, dir := newPlannedNode(t, "old")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, terraform.PlanFile), []byte("plan"), 0600))
+	require.NoError(t, storePlanMetadata(n))
+
+	changed := new(graph.NodeMock)
+	changed.On("Path").Return(dir)
+	changed.On("Hash").Return("new", nil)
+
+	assert.Error(t, checkPlan(changed, false))
+}
+
+func TestCheckPlanWithoutMetadata(t *testing.T) {
+	n, dir := newPlannedNode(t, "hash")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, terraform.PlanFile), []byte("plan"), 0600))
+
+	assert.Error(t, checkPlan(n, false))
+}
@@ -0,0 +1,172 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/rs/zerolog/log"
+)
+
+// ProtectedResources contains the patterns of the resources that may not be deleted or replaced by apply, globally
+// and for every component
+type ProtectedResources struct {
+	global     []string
+	components map[string][]string
+}
+
+// NewProtectedResources creates the protected resources from the configuration, and verifies the patterns are valid.
+// The global patterns apply to every resource, the patterns of a component only to the resources of that component.
+func NewProtectedResources(cfg *config.MachConfig) (*ProtectedResources, error) {
+	if err := validateProtectedPatterns(cfg.MachComposer.ProtectedResources); err != nil {
+		return nil, err
+	}
+
+	p := &ProtectedResources{global: cfg.MachComposer.ProtectedResources, components: map[string][]string{}}
+	for _, c := range cfg.Components {
+		if len(c.ProtectedResources) == 0 {
+			continue
+		}
+
+		if err := validateProtectedPatterns(c.ProtectedResources); err != nil {
+			return nil, fmt.Errorf("component %s: %w", c.Name, err)
+		}
+		p.components[c.Name] = c.ProtectedResources
+	}
+
+	return p, nil
+}
+
+func validateProtectedPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid protected resource pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// protectionRule contains patterns that only apply to the resources of which the address starts with the scope
+type protectionRule struct {
+	scope    string
+	patterns []string
+}
+
+// rules returns the rules that apply to the node. The patterns of a component are scoped to the module of the
+// component, so they also apply to the components deployed as part of a site without affecting the other components
+// in the site.
+func (p *ProtectedResources) rules(n graph.Node) []protectionRule {
+	if p == nil {
+		return nil
+	}
+
+	var rules []protectionRule
+	if len(p.global) > 0 {
+		rules = append(rules, protectionRule{patterns: p.global})
+	}
+	for _, sc := range deployedComponents(n) {
+		if patterns, ok := p.components[sc.SiteComponentConfig.Name]; ok {
+			rules = append(rules, protectionRule{
+				scope:    fmt.Sprintf("module.%s.", sc.SiteComponentConfig.Name),
+				patterns: patterns,
+			})
+		}
+	}
+	return rules
+}
+
+// protected returns true if the resource type or address matches any of the patterns of a rule that applies to the
+// resource
+func protected(r terraform.Resource, rules []protectionRule) bool {
+	for _, rule := range rules {
+		if !strings.HasPrefix(r.Address, rule.scope) {
+			continue
+		}
+		for _, pattern := range rule.patterns {
+			if ok, _ := path.Match(pattern, r.Type); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, r.Address); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ProtectedResourcesError is returned if the plan of a node deletes or replaces protected resources
+type ProtectedResourcesError struct {
+	Path      string
+	Addresses []string
+}
+
+func (e *ProtectedResourcesError) Error() string {
+	return fmt.Sprintf("the plan for %s deletes or replaces protected resources (%s), use --allow-destroy to "+
+		"apply it anyway", e.Path, strings.Join(e.Addresses, ", "))
+}
+
+// protectedAddresses returns the offending addresses if the error is a ProtectedResourcesError
+func protectedAddresses(err error) []string {
+	var perr *ProtectedResourcesError
+	if errors.As(err, &perr) {
+		return perr.Addresses
+	}
+	return nil
+}
+
+// checkProtectedResources verifies that the saved plan of the node does not delete or replace any resources matching
+// the protected patterns of the node, so exactly the verified plan is applied. A node without saved plan is planned
+// first when auto-approving. Otherwise terraform would ask to confirm a plan that was never verified, so an error is
+// returned instead. A plan that was rejected is kept, so it can be reviewed and applied with --allow-destroy.
+func (gr *GraphRunner) checkProtectedResources(ctx context.Context, n graph.Node, autoApprove bool) error {
+	rules := gr.protectedResources.rules(n)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	ok, err := terraform.HasPlan(n.Path())
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		if !autoApprove {
+			return fmt.Errorf("%s has no saved plan to verify the protected resources against, run mach-composer "+
+				"plan first or use --plan-first, --auto-approve or --allow-destroy", n.Identifier())
+		}
+
+		log.Info().Msgf("Planning %s to verify that no protected resources are destroyed", n.Identifier())
+		if _, err = terraform.Plan(ctx, n.Path(), true); err != nil {
+			return err
+		}
+		if err = storePlanMetadata(n); err != nil {
+			return err
+		}
+	}
+
+	out, err := terraform.ShowJSON(ctx, n.Path())
+	if err != nil {
+		return err
+	}
+
+	resources, err := terraform.ParseDestroyedResources([]byte(out))
+	if err != nil {
+		return fmt.Errorf("failed to inspect plan of %s: %w", n.Path(), err)
+	}
+
+	var addresses []string
+	for _, r := range resources {
+		if protected(r, rules) {
+			addresses = append(addresses, r.Address)
+		}
+	}
+	if len(addresses) > 0 {
+		return &ProtectedResourcesError{Path: n.Path(), Addresses: addresses}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProtectedResources(t *testing.T) {
+	p, err := NewProtectedResources(&config.MachConfig{
+		MachComposer: config.MachComposer{ProtectedResources: []string{"commercetools_project"}},
+		Components: []config.ComponentConfig{
+			{Name: "api", ProtectedResources: []string{"module.api.aws_s3_bucket.*"}},
+			{Name: "other"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"commercetools_project"}, p.global)
+	assert.Equal(t, map[string][]string{"api": {"module.api.aws_s3_bucket.*"}}, p.components)
+
+	_, err = NewProtectedResources(&config.MachConfig{
+		Components: []config.ComponentConfig{{Name: "api", ProtectedResources: []string{"aws_s3_bucket.["}}},
+	})
+	assert.ErrorContains(t, err, "component api: invalid protected resource pattern")
+}
+
+func TestProtected(t *testing.T) {
+	r := terraform.Resource{Address: "module.api.aws_s3_bucket.main", Type: "aws_s3_bucket"}
+
+	assert.True(t, protected(r, []protectionRule{{patterns: []string{"aws_s3_bucket"}}}))
+	assert.True(t, protected(r, []protectionRule{{patterns: []string{"commercetools_project", "module.api.*"}}}))
+	assert.False(t, protected(r, []protectionRule{{patterns: []string{"aws_s3_bucket.*", "module.other.*"}}}))
+	assert.False(t, protected(r, nil))
+
+	// Scoped patterns only apply to the resources in the scope
+	assert.True(t, protected(r, []protectionRule{{scope: "module.api.", patterns: []string{"aws_s3_bucket"}}}))
+	assert.False(t, protected(r, []protectionRule{{scope: "module.payment.", patterns: []string{"aws_s3_bucket"}}}))
+}
+
+func TestProtectedResourcesSiteDeployment(t *testing.T) {
+	p, err := NewProtectedResources(&config.MachConfig{
+		MachComposer: config.MachComposer{ProtectedResources: []string{"commercetools_project"}},
+		Components: []config.ComponentConfig{
+			{Name: "api", ProtectedResources: []string{"aws_s3_bucket"}},
+		},
+	})
+	require.NoError(t, err)
+
+	// The patterns of the components deployed as part of the site apply to the site, scoped to their module
+	site := newSiteTestGraph(t, &config.ComponentConfig{Name: "api"}, &config.ComponentConfig{Name: "payment"})
+	rules := p.rules(site)
+	assert.Equal(t, []protectionRule{
+		{patterns: []string{"commercetools_project"}},
+		{scope: "module.api.", patterns: []string{"aws_s3_bucket"}},
+	}, rules)
+
+	assert.True(t, protected(terraform.Resource{Address: "module.api.aws_s3_bucket.main", Type: "aws_s3_bucket"}, rules))
+	assert.False(t, protected(terraform.Resource{Address: "module.payment.aws_s3_bucket.main", Type: "aws_s3_bucket"}, rules))
+	assert.True(t, protected(terraform.Resource{Address: "module.payment.commercetools_project.main", Type: "commercetools_project"}, rules))
+}
+
+func TestGraphRunnerApplyProtectedResources(t *testing.T) {
+	g := newTestGraph(t, t.TempDir(), config.DeploymentSiteComponent, gitComponent("component"))
+
+	n := testNode(t, g, graph.SiteComponentType)
+
+	newRecorder := func() *terraform.RecordingExecutor {
+		return &terraform.RecordingExecutor{
+			Respond: func(c terraform.RecordedCommand) (string, error) {
+				if c.Args[0] == "show" {
+					return `{"resource_changes": [
+						{"address": "commercetools_project.main", "type": "commercetools_project", "change": {"actions": ["delete", "create"]}}
+					]}`, nil
+				}
+				return "", nil
+			},
+		}
+	}
+
+	r := &GraphRunner{workers: 1, hash: hash.NewMemoryMapHandler(), batch: batcher.NaiveBatchFunc()}
+	r.SetProtectedResources(&ProtectedResources{global: []string{"commercetools_project"}})
+	opts := &ApplyOptions{AutoApprove: true, RequirePlan: true, IgnoreChangeDetection: true, Targets: graph.Vertices{n}}
+
+	savePlan(t, n)
+	recorder := newRecorder()
+	err := r.TerraformApply(terraform.WithExecutor(context.Background(), recorder), g, opts)
+
+	var grouped *cli.GroupedError
+	require.ErrorAs(t, err, &grouped)
+	assert.Equal(t, []string{"commercetools_project.main"}, protectedAddresses(grouped.Errors[0]))
+	for _, c := range recorder.Commands() {
+		assert.NotEqual(t, "apply", c.Args[0])
+	}
+
+	opts.AllowDestroy = true
+	recorder = newRecorder()
+	require.NoError(t, r.TerraformApply(terraform.WithExecutor(context.Background(), recorder), g, opts))
+	assert.Contains(t, recorder.Commands(), terraform.RecordedCommand{Path: n.Path(), Args: []string{"apply", "-auto-approve", terraform.PlanFile}})
+}
+
+func TestGraphRunnerApplyProtectedResourcesWithoutPlan(t *testing.T) {
+	g := newTestGraph(t, t.TempDir(), config.DeploymentSiteComponent, gitComponent("component"))
+	n := testNode(t, g, graph.SiteComponentType)
+	savePlan(t, n)
+	require.NoError(t, terraform.RemovePlan(n.Path()))
+	initializeNodes(t, n.Path())
+
+	destroyed := true
+	recorder := &terraform.RecordingExecutor{
+		Respond: func(c terraform.RecordedCommand) (string, error) {
+			switch {
+			case c.Args[0] == "plan":
+				return "", os.WriteFile(filepath.Join(c.Path, terraform.PlanFile), []byte("plan"), 0600)
+			case c.Args[0] == "show" && destroyed:
+				return `{"resource_changes": [
+					{"address": "commercetools_project.main", "type": "commercetools_project", "change": {"actions": ["delete"]}}
+				]}`, nil
+			}
+			return "{}", nil
+		},
+	}
+	ctx := terraform.WithExecutor(context.Background(), recorder)
+
+	r := &GraphRunner{workers: 1, hash: hash.NewMemoryMapHandler(), batch: batcher.NaiveBatchFunc()}
+	r.SetProtectedResources(&ProtectedResources{global: []string{"commercetools_project"}})
+	opts := &ApplyOptions{IgnoreChangeDetection: true, Targets: graph.Vertices{n}}
+
+	// Terraform would ask to confirm a plan that was not verified
+	err := r.TerraformApply(ctx, g, opts)
+	var grouped *cli.GroupedError
+	require.ErrorAs(t, err, &grouped)
+	assert.ErrorContains(t, grouped.Errors[0], "has no saved plan")
+	assert.Empty(t, recorder.Commands())
+
+	// When auto-approving the node is planned first, and the plan is kept so it can be reviewed
+	opts.AutoApprove = true
+	err = r.TerraformApply(ctx, g, opts)
+	require.ErrorAs(t, err, &grouped)
+	assert.Equal(t, []string{"commercetools_project.main"}, protectedAddresses(grouped.Errors[0]))
+	assert.Equal(t, []terraform.RecordedCommand{
+		{Path: n.Path(), Args: []string{"plan", "-out=" + terraform.PlanFile}},
+		{Path: n.Path(), Args: []string{"show", "-json", filepath.Join(n.Path(), terraform.PlanFile)}},
+	}, recorder.Commands())
+	assert.FileExists(t, filepath.Join(n.Path(), terraform.PlanFile))
+
+	// The verified plan is applied
+	require.NoError(t, terraform.RemovePlan(n.Path()))
+	destroyed = false
+	recorder = &terraform.RecordingExecutor{Respond: recorder.Respond}
+	require.NoError(t, r.TerraformApply(terraform.WithExecutor(context.Background(), recorder), g, opts))
+	assert.Equal(t, []terraform.RecordedCommand{
+		{Path: n.Path(), Args: []string{"plan", "-out=" + terraform.PlanFile}},
+		{Path: n.Path(), Args: []string{"show", "-json", filepath.Join(n.Path(), terraform.PlanFile)}},
+		{Path: n.Path(), Args: []string{"apply", "-auto-approve", terraform.PlanFile}},
+	}, recorder.Commands()[:3])
+}
@@ -0,0 +1,197 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+)
+
+// NodeStatus is the outcome of a node in a single run
+type NodeStatus string
+
+const (
+	StatusSucceeded           NodeStatus = "succeeded"
+	StatusFailed              NodeStatus = "failed"
+	StatusTimedOut            NodeStatus = "timed-out"
+	StatusSkippedByDependency NodeStatus = "skipped-by-dependency"
+	StatusUnchanged           NodeStatus = "unchanged"
+	StatusNotSelected         NodeStatus = "not-selected"
+	StatusNoPlan              NodeStatus = "no-plan"
+)
+
+type reportEntry struct {
+	node   graph.Node
+	status NodeStatus
+	// attempts is the number of times the node was run, which is more than one if it was retried
+	attempts int
+	// lock is the state lock that might still be held after the last attempt
+	lock *stateLock
+	// protected contains the addresses of the protected resources the saved plan would delete or replace
+	protected []string
+}
+
+// runReport keeps track of the outcome of every node in a run. It is safe for concurrent use.
+type runReport struct {
+	mu      sync.Mutex
+	g       *graph.Graph
+	entries map[string]*reportEntry
+}
+
+func newRunReport(g *graph.Graph) *runReport {
+	return &runReport{
+		g:       g,
+		entries: map[string]*reportEntry{},
+	}
+}
+
+// entry returns the entry of the node, which is created if it does not exist yet. The caller must hold the lock.
+func (r *runReport) entry(n graph.Node) *reportEntry {
+	e, ok := r.entries[n.Path()]
+	if !ok {
+		e = &reportEntry{node: n}
+		r.entries[n.Path()] = e
+	}
+	return e
+}
+
+func (r *runReport) set(n graph.Node, status NodeStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entry(n).status = status
+}
+
+func (r *runReport) setAttempts(n graph.Node, attempts int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entry(n).attempts = attempts
+}
+
+func (r *runReport) setLock(n graph.Node, l *stateLock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entry(n).lock = l
+}
+
+func (r *runReport) setProtected(n graph.Node, addresses []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entry(n).protected = addresses
+}
+
+// paths returns the paths of the nodes in the report in order. The caller must hold the lock.
+func (r *runReport) paths() []string {
+	var paths []string
+	for p := range r.entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// retried returns true if any node was run more than once
+func (r *runReport) retried() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.entries {
+		if e.attempts > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *runReport) status(path string) NodeStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.entries[path]; ok {
+		return e.status
+	}
+	return ""
+}
+
+// blocked returns true if any of the given parents failed, timed out or was skipped because one of its own dependencies
+// failed. Parents that were skipped because they are unchanged or not selected did not fail, but might still depend on a
+// failed node, so their own upstream nodes are checked as well. The same applies to parents without a saved plan.
+func (r *runReport) blocked(parents []string, upstream map[string][]string) bool {
+	for _, p := range parents {
+		switch r.status(p) {
+		case StatusFailed, StatusTimedOut, StatusSkippedByDependency:
+			return true
+		case StatusUnchanged, StatusNotSelected, StatusNoPlan:
+			if r.blocked(upstream[p], upstream) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// noteworthy returns true if any node was retried, timed out or would destroy protected resources, in which case the
+// report is written at the end of every run instead of only in keep-going mode
+func (r *runReport) noteworthy() bool {
+	if r.retried() {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.entries {
+		if e.status == StatusTimedOut || len(e.protected) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders the report as a table, ordered by node path. The number of attempts is included if any node was
+// retried. The protected resources that blocked a node are listed below the table.
+func (r *runReport) Write(w io.Writer) {
+	retried := r.retried()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths := r.paths()
+
+	header := []string{"Node", "Type", "Status"}
+	if retried {
+		header = append(header, "Attempts")
+	}
+
+	var data [][]string
+	for _, p := range paths {
+		e := r.entries[p]
+		row := []string{r.g.RelativePath(e.node), string(e.node.Type()), string(e.status)}
+		if retried && e.attempts > 0 {
+			row = append(row, strconv.Itoa(e.attempts))
+		} else if retried {
+			row = append(row, "")
+		}
+		data = append(data, row)
+	}
+
+	cli.WriteTable(w, header, data)
+
+	for _, p := range paths {
+		e := r.entries[p]
+		if len(e.protected) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%s would delete or replace protected resources:\n", r.g.RelativePath(e.node))
+		for _, a := range e.protected {
+			_, _ = fmt.Fprintf(w, " - %s\n", a)
+		}
+	}
+}
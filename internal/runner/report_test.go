@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestGraphRunnerKeepGoing(t *testing.T) {
+	for _, strategy := range []Strategy{BatchStrategy, DependencyStrategy} {
+		t.Run(string(strategy), func(t *testing.T) {
+			runner := GraphRunner{workers: 1, strategy: strategy}
+			runner.hash = hash.NewMemoryMapHandler()
+			runner.batch = batcher.NaiveBatchFunc()
+
+			var mu sync.Mutex
+			var called []string
+			var buf bytes.Buffer
+
+			err := runner.run(context.Background(), newStrategyTestGraph(), func(ctx context.Context, node internalgraph.Node) (string, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				called = append(called, node.Identifier())
+				if node.Identifier() == "component-2" {
+					return "", assert.AnError
+				}
+				return "", nil
+			}, &runOptions{KeepGoing: true, ReportWriter: &buf})
+
+			cliErr := &cli.GroupedError{}
+			assert.ErrorAs(t, err, &cliErr)
+			assert.Len(t, cliErr.Errors, 1)
+			assert.ElementsMatch(t, []string{"site-1", "component-1", "component-2"}, called)
+			assert.Contains(t, buf.String(), "component-2")
+		})
+	}
+}
+
+func TestGraphRunnerKeepGoingSkippedIntermediate(t *testing.T) {
+	runner := &GraphRunner{workers: 1, batch: batcher.NaiveBatchFunc()}
+	for strategy, run := range map[Strategy]func(context.Context, *internalgraph.Graph, executorFunc, *runOptions, *runReport) error{
+		BatchStrategy:      runner.runBatches,
+		DependencyStrategy: runner.runDependencies,
+	} {
+		t.Run(string(strategy), func(t *testing.T) {
+			// component-2 is unchanged, but depends on site-1 which fails, so component-3 must not run either
+			g := newStrategyTestGraph()
+			for _, p := range []string{"site-1", "component-1", "component-3"} {
+				n, _ := g.Vertex(p)
+				n.SetTainted(true)
+			}
+
+			var mu sync.Mutex
+			var called []string
+			report := newRunReport(g)
+			err := run(context.Background(), g, func(ctx context.Context, node internalgraph.Node) (string, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				called = append(called, node.Identifier())
+				if node.Identifier() == "site-1" {
+					return "", assert.AnError
+				}
+				return "", nil
+			}, &runOptions{KeepGoing: true, ReportWriter: io.Discard}, report)
+
+			assert.Error(t, err)
+			assert.Equal(t, []string{"site-1"}, called)
+			assert.Equal(t, StatusUnchanged, report.status("component-2"))
+			assert.Equal(t, StatusSkippedByDependency, report.status("component-3"))
+		})
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	g := newStrategyTestGraph()
+	report := newRunReport(g)
+
+	for p, status := range map[string]NodeStatus{
+		"site-1":      StatusUnchanged,
+		"component-1": StatusSucceeded,
+		"component-2": StatusFailed,
+		"component-3": StatusSkippedByDependency,
+	} {
+		n, _ := g.Vertex(p)
+		report.set(n, status)
+	}
+
+	assert.True(t, report.blocked([]string{"component-1", "component-2"}, nil))
+	assert.True(t, report.blocked([]string{"component-3"}, nil))
+	assert.False(t, report.blocked([]string{"site-1", "component-1"}, nil))
+
+	var buf bytes.Buffer
+	report.Write(&buf)
+	assert.Contains(t, buf.String(), "component-3")
+	assert.Contains(t, buf.String(), string(StatusSkippedByDependency))
+}
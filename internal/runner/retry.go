@@ -0,0 +1,244 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultRetryBackoff    = 10 * time.Second
+	defaultRetryMaxBackoff = 5 * time.Minute
+)
+
+// defaultRetryableErrors match the transient failures that are retried if no retryable errors are configured: state
+// lock contention, provider registry and network failures, and rate limiting
+var defaultRetryableErrors = []string{
+	"Error acquiring the state lock",
+	"Failed to query available provider packages",
+	"Failed to install provider",
+	"TLS handshake timeout",
+	"i/o timeout",
+	"connection reset by peer",
+	"(?i)too many requests",
+	"(?i)rate limit",
+	"(?i)service unavailable",
+}
+
+// RetryPolicy determines whether and when a failed node is run again
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one
+	MaxAttempts int
+	// Backoff is the time to wait before the first retry. It is doubled on every next retry, up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// RetryableErrors are matched against the error output of a failed attempt. If empty, every failure is retryable
+	RetryableErrors []*regexp.Regexp
+}
+
+// NewRetryPolicy creates the policy from the global configuration, overridden by the fields that are set in the
+// component configuration. Both can be nil.
+func NewRetryPolicy(global, component *config.RetryConfig) (*RetryPolicy, error) {
+	merged := config.RetryConfig{}
+	for _, c := range []*config.RetryConfig{global, component} {
+		if c == nil {
+			continue
+		}
+		if c.MaxAttempts > 0 {
+			merged.MaxAttempts = c.MaxAttempts
+		}
+		if c.Backoff > 0 {
+			merged.Backoff = c.Backoff
+		}
+		if c.MaxBackoff > 0 {
+			merged.MaxBackoff = c.MaxBackoff
+		}
+		if len(c.RetryableErrors) > 0 {
+			merged.RetryableErrors = c.RetryableErrors
+		}
+	}
+
+	p := &RetryPolicy{
+		MaxAttempts: max(merged.MaxAttempts, 1),
+		Backoff:     merged.Backoff,
+		MaxBackoff:  merged.MaxBackoff,
+	}
+	if p.Backoff == 0 {
+		p.Backoff = defaultRetryBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if len(merged.RetryableErrors) == 0 {
+		merged.RetryableErrors = defaultRetryableErrors
+	}
+
+	for _, expr := range merged.RetryableErrors {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retryable error %q: %w", expr, err)
+		}
+		p.RetryableErrors = append(p.RetryableErrors, re)
+	}
+
+	return p, nil
+}
+
+// retryable returns true if the failure with the given error output should be retried
+func (p *RetryPolicy) retryable(output string) bool {
+	if len(p.RetryableErrors) == 0 {
+		return true
+	}
+	for _, re := range p.RetryableErrors {
+		if re.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the time to wait after the given failed attempt, starting at 1
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxBackoff)
+}
+
+// RetryPolicies contains the retry policy of every component, and the global policy that applies to the other nodes
+type RetryPolicies struct {
+	global     *RetryPolicy
+	components map[string]*RetryPolicy
+}
+
+// NewRetryPolicies creates the retry policies from the configuration, and verifies the retryable errors are valid
+// regular expressions
+func NewRetryPolicies(cfg *config.MachConfig) (*RetryPolicies, error) {
+	global, err := NewRetryPolicy(cfg.MachComposer.Retry, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := &RetryPolicies{global: global, components: map[string]*RetryPolicy{}}
+	for _, c := range cfg.Components {
+		if c.Retry == nil {
+			continue
+		}
+
+		p, err := NewRetryPolicy(cfg.MachComposer.Retry, c.Retry)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", c.Name, err)
+		}
+		policies.components[c.Name] = p
+	}
+
+	return policies, nil
+}
+
+// policy returns the policy that applies to the node. Without policies a node is attempted once. A site that deploys
+// components with their own policy combines the policies of all its components, as a command on the site runs all of
+// them.
+func (p *RetryPolicies) policy(n graph.Node) *RetryPolicy {
+	if p == nil {
+		return &RetryPolicy{MaxAttempts: 1}
+	}
+
+	var policies []*RetryPolicy
+	for _, sc := range deployedComponents(n) {
+		if cp, ok := p.components[sc.SiteComponentConfig.Name]; ok {
+			policies = append(policies, cp)
+		} else {
+			policies = append(policies, p.global)
+		}
+	}
+
+	switch len(policies) {
+	case 0:
+		return p.global
+	case 1:
+		return policies[0]
+	default:
+		return combineRetryPolicies(policies)
+	}
+}
+
+// combineRetryPolicies returns a policy that retries whenever any of the policies would, using the largest number of
+// attempts and delays
+func combineRetryPolicies(policies []*RetryPolicy) *RetryPolicy {
+	combined := &RetryPolicy{}
+	retryAll := false
+	for _, p := range policies {
+		combined.MaxAttempts = max(combined.MaxAttempts, p.MaxAttempts)
+		combined.Backoff = max(combined.Backoff, p.Backoff)
+		combined.MaxBackoff = max(combined.MaxBackoff, p.MaxBackoff)
+		retryAll = retryAll || len(p.RetryableErrors) == 0
+		combined.RetryableErrors = append(combined.RetryableErrors, p.RetryableErrors...)
+	}
+
+	// A policy without retryable errors retries every failure, so the combined policy does as well
+	if retryAll {
+		combined.RetryableErrors = nil
+	}
+	return combined
+}
+
+// executeWithRetry runs the executor on the node, and runs it again according to the retry policy of the node if it
+// fails. The number of attempts is recorded in the report.
+func (gr *GraphRunner) executeWithRetry(ctx context.Context, g *graph.Graph, f executorFunc, n graph.Node, report *runReport) (string, error) {
+	p := gr.retryPolicies.policy(n)
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			log.Info().Msgf("Running attempt %d of %d on %s", attempt, p.MaxAttempts, n.Identifier())
+		}
+
+		stderr := &lockedBuffer{}
+		out, err := gr.execute(withAttempt(ctx, attempt), g, f, n, stderr)
+		report.setAttempts(n, attempt)
+		report.setLock(n, heldLock(err, stderr.buf.String()))
+		report.setProtected(n, protectedAddresses(err))
+		if err == nil {
+			if attempt > 1 {
+				log.Info().Msgf("%s succeeded after %d attempts", n.Identifier(), attempt)
+			}
+			return out, nil
+		}
+
+		if attempt >= p.MaxAttempts || ctx.Err() != nil || protectedAddresses(err) != nil ||
+			!p.retryable(stderr.buf.String()+"\n"+err.Error()) {
+			if attempt > 1 {
+				log.Error().Err(err).Msgf("Attempt %d of %d on %s failed, giving up", attempt, p.MaxAttempts, n.Identifier())
+			}
+			return out, err
+		}
+
+		delay := p.delay(attempt)
+		log.Warn().Err(err).Msgf("Attempt %d of %d on %s failed, retrying in %s", attempt, p.MaxAttempts,
+			n.Identifier(), delay)
+
+		select {
+		case <-ctx.Done():
+			return out, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+type attemptKey struct{}
+
+// withAttempt returns a context that contains the number of the attempt, starting at 1
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// isRetry returns true if the node is run again after a failed attempt
+func isRetry(ctx context.Context) bool {
+	attempt, ok := ctx.Value(attemptKey{}).(int)
+	return ok && attempt > 1
+}
@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRetryPolicy(t *testing.T) {
+	p, err := NewRetryPolicy(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.MaxAttempts)
+
+	// Only transient failures are retried by default
+	p, err = NewRetryPolicy(&config.RetryConfig{MaxAttempts: 3}, nil)
+	require.NoError(t, err)
+	assert.True(t, p.retryable("Error: Error acquiring the state lock"))
+	assert.True(t, p.retryable("Error: 429 Too Many Requests"))
+	assert.False(t, p.retryable("Error: Unsupported argument"))
+
+	p, err = NewRetryPolicy(&config.RetryConfig{
+		MaxAttempts:     3,
+		Backoff:         time.Second,
+		RetryableErrors: []string{"state lock"},
+	}, &config.RetryConfig{
+		MaxAttempts: 5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, p.MaxAttempts)
+	assert.Equal(t, time.Second, p.Backoff)
+	assert.Equal(t, defaultRetryMaxBackoff, p.MaxBackoff)
+	assert.True(t, p.retryable("Error acquiring the state lock"))
+	assert.False(t, p.retryable("Error: Unsupported argument"))
+
+	_, err = NewRetryPolicy(&config.RetryConfig{RetryableErrors: []string{"("}}, nil)
+	assert.Error(t, err)
+}
+
+func TestRetryPoliciesSiteDeployment(t *testing.T) {
+	policies, err := NewRetryPolicies(&config.MachConfig{
+		MachComposer: config.MachComposer{Retry: &config.RetryConfig{MaxAttempts: 2, RetryableErrors: []string{"state lock"}}},
+		Components: []config.ComponentConfig{
+			{Name: "api", Retry: &config.RetryConfig{MaxAttempts: 4, RetryableErrors: []string{"rate limit"}}},
+		},
+	})
+	require.NoError(t, err)
+
+	// The components deployed as part of the site are run by the site node, so their policies apply to the site
+	site := newSiteTestGraph(t, &config.ComponentConfig{Name: "api"}, &config.ComponentConfig{Name: "payment"})
+	p := policies.policy(site)
+	assert.Equal(t, 4, p.MaxAttempts)
+	assert.True(t, p.retryable("Error acquiring the state lock"))
+	assert.True(t, p.retryable("rate limit exceeded"))
+	assert.False(t, p.retryable("Error: Unsupported argument"))
+
+	assert.Equal(t, policies.global, policies.policy(newSiteTestGraph(t, &config.ComponentConfig{Name: "payment"})))
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := &RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.delay(1))
+	assert.Equal(t, 2*time.Second, p.delay(2))
+	assert.Equal(t, 4*time.Second, p.delay(3))
+	assert.Equal(t, 5*time.Second, p.delay(4))
+}
+
+func TestGraphRunnerRetry(t *testing.T) {
+	for _, strategy := range []Strategy{BatchStrategy, DependencyStrategy} {
+		t.Run(string(strategy), func(t *testing.T) {
+			runner := GraphRunner{workers: 1, strategy: strategy}
+			runner.hash = hash.NewMemoryMapHandler()
+			runner.batch = batcher.NaiveBatchFunc()
+			runner.retryPolicies = &RetryPolicies{global: &RetryPolicy{
+				MaxAttempts:     3,
+				Backoff:         time.Millisecond,
+				MaxBackoff:      time.Millisecond,
+				RetryableErrors: []*regexp.Regexp{regexp.MustCompile("Error acquiring the state lock")},
+			}}
+
+			var mu sync.Mutex
+			attempts := map[string]int{}
+			dir := t.TempDir()
+
+			err := runner.run(context.Background(), newStrategyTestGraph(), func(ctx context.Context, n internalgraph.Node) (string, error) {
+				mu.Lock()
+				attempts[n.Identifier()]++
+				attempt := attempts[n.Identifier()]
+				mu.Unlock()
+
+				switch {
+				case n.Identifier() == "component-1" && attempt == 1:
+					return utils.RunInteractive(ctx, true, "sh", dir, "-c", "echo 'Error acquiring the state lock' >&2; exit 1")
+				case n.Identifier() == "component-2":
+					return utils.RunInteractive(ctx, true, "sh", dir, "-c", "echo 'Error: Unsupported argument' >&2; exit 1")
+				}
+				return "", nil
+			}, &runOptions{KeepGoing: true})
+			require.Error(t, err)
+
+			// Only the failure matching the retryable errors is retried
+			assert.Equal(t, 2, attempts["component-1"])
+			assert.Equal(t, 1, attempts["component-2"])
+			assert.Equal(t, 1, attempts["site-1"])
+		})
+	}
+}
+
+func TestRunReportAttempts(t *testing.T) {
+	g := newStrategyTestGraph()
+	report := newRunReport(g)
+
+	n, _ := g.Vertex("component-1")
+	report.setAttempts(n, 1)
+	report.set(n, StatusSucceeded)
+	assert.False(t, report.retried())
+
+	report.setAttempts(n, 2)
+	assert.True(t, report.retried())
+	assert.Equal(t, StatusSucceeded, report.status("component-1"))
+
+	var buf bytes.Buffer
+	report.Write(&buf)
+	assert.Contains(t, buf.String(), "ATTEMPTS")
+}
+
+func TestGraphRunnerRetryApplyReplans(t *testing.T) {
+	g := newTestGraph(t, t.TempDir(), config.DeploymentSiteComponent, gitComponent("component"))
+
+	n := testNode(t, g, internalgraph.SiteComponentType)
+	savePlan(t, n)
+
+	applied := 0
+	recorder := &terraform.RecordingExecutor{
+		Respond: func(c terraform.RecordedCommand) (string, error) {
+			if c.Args[0] == "apply" {
+				applied++
+				if applied == 1 {
+					return "", errors.New("Error acquiring the state lock")
+				}
+			}
+			return "", nil
+		},
+	}
+
+	r := &GraphRunner{workers: 1, hash: hash.NewMemoryMapHandler(), batch: batcher.NaiveBatchFunc()}
+	r.SetRetryPolicies(&RetryPolicies{global: &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}})
+
+	err := r.TerraformApply(terraform.WithExecutor(context.Background(), recorder), g, &ApplyOptions{
+		AutoApprove:           true,
+		IgnoreChangeDetection: true,
+		Targets:               internalgraph.Vertices{n},
+	})
+	require.NoError(t, err)
+
+	var commands []string
+	for _, c := range recorder.Commands() {
+		if c.Args[0] != "init" {
+			commands = append(commands, c.Args[0])
+		}
+	}
+	// The saved plan is replaced before the apply is retried
+	assert.Equal(t, []string{"apply", "plan", "apply"}, commands)
+}
@@ -2,7 +2,11 @@ package runner
 
 import (
 	"context"
+	"io"
+	"os"
+
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"golang.org/x/exp/maps"
 )
 
 type ApplyOptions struct {
@@ -10,23 +14,170 @@ type ApplyOptions struct {
 	IgnoreChangeDetection bool
 	Destroy               bool
 	AutoApprove           bool
+	KeepGoing             bool
+	// ReplanStale discards saved plans that do not match the current configuration instead of failing, so terraform
+	// plans again during apply
+	ReplanStale bool
+	// RequirePlan only applies the nodes that have a saved plan, which was approved beforehand
+	RequirePlan bool
+	// AllowDestroy applies saved plans that delete or replace protected resources
+	AllowDestroy bool
+	// AllowLayoutChange applies even if the deployment type of components changed without migrating their state
+	AllowLayoutChange bool
+	// DryRun shows the commands that would be run on every node instead of running them
+	DryRun  bool
+	Targets graph.Vertices
 }
 
 type PlanOptions struct {
 	ForceInit             bool
 	IgnoreChangeDetection bool
 	Lock                  bool
+	KeepGoing             bool
+	// DryRun shows the commands that would be run on every node instead of running them
+	DryRun  bool
+	Targets graph.Vertices
+}
+
+// ReviewOptions selects the nodes whose saved plans are reviewed
+type ReviewOptions struct {
+	IgnoreChangeDetection bool
+	Targets               graph.Vertices
+}
+
+type DriftOptions struct {
+	ForceInit bool
+	// RefreshOnly only detects changes made outside of terraform. Otherwise changes in the configuration are reported
+	// as drift as well
+	RefreshOnly bool
+	Targets     graph.Vertices
+}
+
+type OutputsOptions struct {
+	// Components limits the outputs to the components with the given names. If empty all components are included
+	Components []string
+	// ShowSensitive includes the values of sensitive outputs instead of redacting them
+	ShowSensitive bool
+}
+
+// ComponentStateOptions selects the component whose state is modified
+type ComponentStateOptions struct {
+	ForceInit bool
+	Site      string
+	Component string
+}
+
+type ImportOptions struct {
+	ComponentStateOptions
+	// Address is the address of the resource within the component
+	Address string
+	ID      string
+}
+
+type StateMoveOptions struct {
+	ComponentStateOptions
+	// Source and Destination are addresses within the component
+	Source      string
+	Destination string
+	DryRun      bool
+}
+
+type StateRemoveOptions struct {
+	ComponentStateOptions
+	// Addresses are addresses within the component
+	Addresses []string
+	DryRun    bool
+}
+
+type MigrateStateOptions struct {
+	ForceInit bool
+	// Components limits the migration to the components with the given names. If empty all changed components are
+	// migrated
+	Components []string
+	// DryRun only lists the resources that would be moved
+	DryRun bool
 }
 
 type ProxyOptions struct {
 	IgnoreChangeDetection bool
 	Command               []string
+	Targets               graph.Vertices
 }
 
 type ShowPlanOptions struct {
 	ForceInit             bool
 	IgnoreChangeDetection bool
 	NoColor               bool
+	// JSON writes a single JSON document summarizing the resource changes of all nodes instead of the plans
+	JSON    bool
+	Targets graph.Vertices
+}
+
+// runOptions contains the options that apply to every run of the graph runner
+type runOptions struct {
+	IgnoreChangeDetection bool
+	// Targets limits the run to the given nodes. If empty all nodes are run
+	Targets graph.Vertices
+	// KeepGoing continues running the nodes that do not depend on a failed node instead of aborting the run
+	KeepGoing bool
+	// Reverse runs the nodes in reverse dependency order, so dependents are run before their dependencies
+	Reverse bool
+	// StoredOutputsDigest uses the digest of the referenced outputs that was stored with the hash of a node, instead of
+	// reading the outputs from the terraform state of its parents. Used by runs that must not run terraform to detect
+	// changes, or that do not act on changes made outside mach-composer.
+	StoredOutputsDigest bool
+	// ReportWriter receives the report of the run. Defaults to stdout, it must be set when stdout is used for
+	// machine-readable output
+	ReportWriter io.Writer
+	// RequirePlan skips the nodes that have no saved plan
+	RequirePlan bool
+}
+
+// reportWriter returns the writer the report of the run is written to
+func (o *runOptions) reportWriter() io.Writer {
+	if o.ReportWriter != nil {
+		return o.ReportWriter
+	}
+	return os.Stdout
+}
+
+// dependencyMaps returns for every node the nodes that have to finish before it can start (upstream), and the nodes
+// that can start once it has finished (downstream). These are swapped when running in reverse.
+func (o *runOptions) dependencyMaps(g *graph.Graph) (upstream, downstream map[string][]string, err error) {
+	pm, err := g.PredecessorMap()
+	if err != nil {
+		return nil, nil, err
+	}
+	am, err := g.AdjacencyMap()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parents := make(map[string][]string, len(pm))
+	for p, edges := range pm {
+		parents[p] = maps.Keys(edges)
+	}
+	children := make(map[string][]string, len(am))
+	for p, edges := range am {
+		children[p] = maps.Keys(edges)
+	}
+
+	if o.Reverse {
+		return children, parents, nil
+	}
+	return parents, children, nil
+}
+
+func (o *runOptions) targetSet() map[string]bool {
+	if len(o.Targets) == 0 {
+		return nil
+	}
+
+	targets := make(map[string]bool, len(o.Targets))
+	for _, n := range o.Targets {
+		targets[n.Path()] = true
+	}
+	return targets
 }
 
 type Runner interface {
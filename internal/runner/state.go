@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/rs/zerolog/log"
+)
+
+// stateFunc modifies the state of a component, which is kept in the state of the given node
+type stateFunc func(ctx context.Context, n graph.Node, state *graph.ComponentState) (string, error)
+
+// runOnComponentState runs the function on the node that holds the state of the component, so that addresses
+// within the component can be mapped to the addresses in that state
+func (gr *GraphRunner) runOnComponentState(ctx context.Context, dg *graph.Graph, opts *ComponentStateOptions, f stateFunc) error {
+	state, err := graph.ResolveComponentState(dg, opts.Site, opts.Component)
+	if err != nil {
+		return err
+	}
+
+	if state.ModulePrefix != "" {
+		log.Info().Msgf("Component %s is deployed as part of site %s; using the site state", opts.Component, opts.Site)
+	}
+
+	_, err = gr.execute(ctx, dg, func(ctx context.Context, n graph.Node) (string, error) {
+		if !terraformIsInitialized(n.Path()) || opts.ForceInit {
+			log.Info().Msgf("Running terraform init for %s", n.Path())
+			if out, err := terraform.Init(ctx, n.Path()); err != nil {
+				return out, err
+			}
+		}
+
+		return f(ctx, n, state)
+	}, state.Node, nil)
+	return err
+}
+
+// TerraformImport imports an existing resource into the state of a component
+func (gr *GraphRunner) TerraformImport(ctx context.Context, dg *graph.Graph, opts *ImportOptions) error {
+	return gr.runOnComponentState(ctx, dg, &opts.ComponentStateOptions,
+		func(ctx context.Context, n graph.Node, state *graph.ComponentState) (string, error) {
+			return terraform.Import(ctx, n.Path(), state.Address(opts.Address), opts.ID)
+		})
+}
+
+// TerraformStateMove moves a resource within the state of a component
+func (gr *GraphRunner) TerraformStateMove(ctx context.Context, dg *graph.Graph, opts *StateMoveOptions) error {
+	return gr.runOnComponentState(ctx, dg, &opts.ComponentStateOptions,
+		func(ctx context.Context, n graph.Node, state *graph.ComponentState) (string, error) {
+			return terraform.StateMove(ctx, n.Path(), state.Address(opts.Source), state.Address(opts.Destination),
+				opts.DryRun)
+		})
+}
+
+// TerraformStateRemove removes resources from the state of a component, without destroying them
+func (gr *GraphRunner) TerraformStateRemove(ctx context.Context, dg *graph.Graph, opts *StateRemoveOptions) error {
+	return gr.runOnComponentState(ctx, dg, &opts.ComponentStateOptions,
+		func(ctx context.Context, n graph.Node, state *graph.ComponentState) (string, error) {
+			addresses := make([]string, len(opts.Addresses))
+			for i, a := range opts.Addresses {
+				addresses[i] = state.Address(a)
+			}
+			return terraform.StateRemove(ctx, n.Path(), addresses, opts.DryRun)
+		})
+}
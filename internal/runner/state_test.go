@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStateTestGraph creates a graph with a component deployed in the site and a component deployed separately, of
+// which terraform is initialized
+func newStateTestGraph(t *testing.T) *graph.Graph {
+	dir := t.TempDir()
+	g := newTestGraph(t, dir, config.DeploymentSite,
+		config.SiteComponentConfig{Name: "nested"},
+		config.SiteComponentConfig{Name: "separate", Deployment: &config.Deployment{Type: config.DeploymentSiteComponent}},
+	)
+	initializeNodes(t, filepath.Join(dir, "main/site-1"), filepath.Join(dir, "main/site-1/separate"))
+	return g
+}
+
+// recordedArgs returns the arguments of the last command recorded, which must have been run for the node at the path
+// relative to the project
+func recordedArgs(t *testing.T, recorder *terraform.RecordingExecutor, g *graph.Graph, p string) string {
+	commands := recorder.Commands()
+	require.NotEmpty(t, commands)
+
+	c := commands[len(commands)-1]
+	assert.Equal(t, filepath.Join(g.StartNode.Path(), p), c.Path)
+	return strings.Join(c.Args, " ")
+}
+
+func TestGraphRunnerTerraformImport(t *testing.T) {
+	g := newStateTestGraph(t)
+	r := &GraphRunner{}
+	recorder := &terraform.RecordingExecutor{}
+	ctx := terraform.WithExecutor(context.Background(), recorder)
+
+	require.NoError(t, r.TerraformImport(ctx, g, &ImportOptions{
+		ComponentStateOptions: ComponentStateOptions{Site: "site-1", Component: "nested"},
+		Address:               "aws_s3_bucket.main",
+		ID:                    "bucket",
+	}))
+	assert.Equal(t, "import module.nested.aws_s3_bucket.main bucket", recordedArgs(t, recorder, g, "site-1"))
+
+	require.NoError(t, r.TerraformImport(ctx, g, &ImportOptions{
+		ComponentStateOptions: ComponentStateOptions{Site: "site-1", Component: "separate"},
+		Address:               "aws_s3_bucket.main",
+		ID:                    "bucket",
+	}))
+	assert.Equal(t, "import aws_s3_bucket.main bucket", recordedArgs(t, recorder, g, "site-1/separate"))
+}
+
+func TestGraphRunnerTerraformStateCommands(t *testing.T) {
+	g := newStateTestGraph(t)
+	r := &GraphRunner{}
+	recorder := &terraform.RecordingExecutor{}
+	ctx := terraform.WithExecutor(context.Background(), recorder)
+	component := ComponentStateOptions{Site: "site-1", Component: "nested"}
+
+	require.NoError(t, r.TerraformStateMove(ctx, g, &StateMoveOptions{
+		ComponentStateOptions: component,
+		Source:                "aws_s3_bucket.old",
+		Destination:           "aws_s3_bucket.new",
+		DryRun:                true,
+	}))
+	assert.Equal(t, "state mv -dry-run module.nested.aws_s3_bucket.old module.nested.aws_s3_bucket.new",
+		recordedArgs(t, recorder, g, "site-1"))
+
+	require.NoError(t, r.TerraformStateRemove(ctx, g, &StateRemoveOptions{
+		ComponentStateOptions: component,
+		Addresses:             []string{"aws_s3_bucket.a", "aws_s3_bucket.b"},
+	}))
+	assert.Equal(t, "state rm module.nested.aws_s3_bucket.a module.nested.aws_s3_bucket.b",
+		recordedArgs(t, recorder, g, "site-1"))
+
+	err := r.TerraformStateRemove(ctx, g, &StateRemoveOptions{
+		ComponentStateOptions: ComponentStateOptions{Site: "site-1", Component: "unknown"},
+		Addresses:             []string{"aws_s3_bucket.a"},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+)
+
+// TaintReason explains why a node is considered changed by the change detection
+type TaintReason string
+
+const (
+	ReasonOwnChange     TaintReason = "own-change"
+	ReasonParentTainted TaintReason = "parent-tainted"
+	ReasonNeverDeployed TaintReason = "never-deployed"
+)
+
+// NodeChange is the change detection state of a single node
+type NodeChange struct {
+	Path           string                `json:"path"`
+	Type           graph.Type            `json:"type"`
+	DeploymentType config.DeploymentType `json:"deployment_type"`
+	OldHash        string                `json:"old_hash"`
+	NewHash        string                `json:"new_hash"`
+	Tainted        bool                  `json:"tainted"`
+	Reason         TaintReason           `json:"reason,omitempty"`
+}
+
+// StatusReport contains the change detection state of every deployable node in the graph
+type StatusReport struct {
+	Tainted bool         `json:"tainted"`
+	Nodes   []NodeChange `json:"nodes"`
+}
+
+// Status runs the change detection on the graph without running terraform. Referenced upstream outputs are not read
+// from the terraform state; instead the digest stored with the previous hash is used.
+func (gr *GraphRunner) Status(ctx context.Context, g *graph.Graph) (*StatusReport, error) {
+	if err := taintGraph(ctx, g, gr.hash, storedOutputsDigest(gr.hash)); err != nil {
+		return nil, err
+	}
+
+	report := &StatusReport{Nodes: []NodeChange{}}
+	for _, n := range g.Vertices() {
+		if n.Type() == graph.ProjectType {
+			continue
+		}
+
+		newHash, err := n.Hash()
+		if err != nil {
+			return nil, err
+		}
+
+		change := NodeChange{
+			Path:           g.RelativePath(n),
+			Type:           n.Type(),
+			DeploymentType: n.DeploymentType(),
+			OldHash:        n.GetOldHash(),
+			NewHash:        newHash,
+			Tainted:        n.Tainted(),
+		}
+
+		if n.Tainted() {
+			report.Tainted = true
+			change.Reason = taintReason(change.OldHash, newHash)
+		}
+
+		report.Nodes = append(report.Nodes, change)
+	}
+
+	sort.Slice(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].Path < report.Nodes[j].Path
+	})
+
+	return report, nil
+}
+
+func taintReason(oldHash, newHash string) TaintReason {
+	switch {
+	case oldHash == "":
+		return ReasonNeverDeployed
+	case oldHash != newHash:
+		return ReasonOwnChange
+	default:
+		return ReasonParentTainted
+	}
+}
+
+// WriteTable renders the report as a table
+func (r *StatusReport) WriteTable(w io.Writer) {
+	var data [][]string
+	for _, n := range r.Nodes {
+		data = append(data, []string{
+			n.Path, string(n.Type), string(n.DeploymentType), shortHash(n.OldHash), shortHash(n.NewHash),
+			string(n.Reason),
+		})
+	}
+
+	cli.WriteTable(w, []string{"Path", "Type", "Deployment Type", "Old Hash", "New Hash", "Tainted Reason"}, data)
+}
+
+// shortHash abbreviates a hash for display, similar to a short git commit hash
+func shortHash(h string) string {
+	if len(h) > 12 {
+		return h[:12]
+	}
+	return h
+}
+
+// WriteJSON renders the report as an indented JSON document
+func (r *StatusReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
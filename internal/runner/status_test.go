@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStatusNode(path, identifier string, typ internalgraph.Type, deploymentType config.DeploymentType,
+	h string) *internalgraph.NodeMock {
+	n := new(internalgraph.NodeMock)
+	n.On("Identifier").Return(identifier)
+	n.On("Path").Return(path)
+	n.On("Hash").Return(h, nil)
+	n.On("Type").Return(typ)
+	n.On("DeploymentType").Return(deploymentType)
+	return n
+}
+
+func TestGraphRunnerStatus(t *testing.T) {
+	project := newStatusNode("main", "main", internalgraph.ProjectType, config.DeploymentSite, "")
+	site := newStatusNode("main/site-1", "site-1", internalgraph.SiteType, config.DeploymentSite, "site-1")
+	component1 := newStatusNode("main/site-1/component-1", "component-1", internalgraph.SiteComponentType,
+		config.DeploymentSiteComponent, "component-1-changed")
+	component2 := newStatusNode("main/site-1/component-2", "component-2", internalgraph.SiteComponentType,
+		config.DeploymentSiteComponent, "component-2")
+	component3 := newStatusNode("main/site-1/component-3", "component-3", internalgraph.SiteComponentType,
+		config.DeploymentSiteComponent, "component-3")
+
+	g := internalgraph.CreateGraphMock(
+		map[string]internalgraph.Node{
+			"main":                    project,
+			"main/site-1":             site,
+			"main/site-1/component-1": component1,
+			"main/site-1/component-2": component2,
+			"main/site-1/component-3": component3,
+		},
+		project,
+		internalgraph.EdgeMock{Source: "main", Target: "main/site-1"},
+		internalgraph.EdgeMock{Source: "main/site-1", Target: "main/site-1/component-1"},
+		internalgraph.EdgeMock{Source: "main/site-1/component-1", Target: "main/site-1/component-2"},
+		internalgraph.EdgeMock{Source: "main/site-1", Target: "main/site-1/component-3"},
+	)
+
+	r := NewGraphRunner(nil, hash.NewMemoryMapHandler(
+		hash.Entry{Identifier: "site-1", Hash: "site-1"},
+		hash.Entry{Identifier: "component-1", Hash: "component-1"},
+		hash.Entry{Identifier: "component-2", Hash: "component-2"},
+	), 1, BatchStrategy)
+
+	report, err := r.Status(context.Background(), g)
+	require.NoError(t, err)
+
+	assert.True(t, report.Tainted)
+	assert.Equal(t, []NodeChange{
+		{
+			Path: "site-1", Type: internalgraph.SiteType, DeploymentType: config.DeploymentSite,
+			OldHash: "site-1", NewHash: "site-1",
+		},
+		{
+			Path: "site-1/component-1", Type: internalgraph.SiteComponentType,
+			DeploymentType: config.DeploymentSiteComponent, OldHash: "component-1", NewHash: "component-1-changed",
+			Tainted: true, Reason: ReasonOwnChange,
+		},
+		{
+			Path: "site-1/component-2", Type: internalgraph.SiteComponentType,
+			DeploymentType: config.DeploymentSiteComponent, OldHash: "component-2", NewHash: "component-2",
+			Tainted: true, Reason: ReasonParentTainted,
+		},
+		{
+			Path: "site-1/component-3", Type: internalgraph.SiteComponentType,
+			DeploymentType: config.DeploymentSiteComponent, OldHash: "", NewHash: "component-3",
+			Tainted: true, Reason: ReasonNeverDeployed,
+		},
+	}, report.Nodes)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, report.WriteJSON(buf))
+
+	var decoded StatusReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, *report, decoded)
+
+	buf.Reset()
+	report.WriteTable(buf)
+	assert.Contains(t, buf.String(), "never-deployed")
+}
@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/rs/zerolog/log"
+)
+
+// Strategy determines how the nodes of a graph are scheduled
+type Strategy string
+
+const (
+	// BatchStrategy groups the nodes in batches based on their depth, and waits for a batch to complete before
+	// starting the next one
+	BatchStrategy Strategy = "batch"
+	// DependencyStrategy starts a node as soon as all its parents have finished
+	DependencyStrategy Strategy = "dependency"
+)
+
+func ParseStrategy(value string) (Strategy, error) {
+	switch Strategy(value) {
+	case BatchStrategy, DependencyStrategy:
+		return Strategy(value), nil
+	default:
+		return "", fmt.Errorf("unknown strategy %s (expected %s or %s)", value, BatchStrategy, DependencyStrategy)
+	}
+}
+
+type nodeResult struct {
+	path string
+	err  error
+}
+
+// runDependencies runs the nodes of the graph as soon as all their parents have finished, with at most the configured
+// number of workers in parallel. Skipped nodes are considered finished right away. When a node fails no new nodes
+// are started, and the run returns once all running nodes are done. In keep-going mode only the descendants of the
+// failed node are skipped. When running in reverse a node is started once all its children have finished instead.
+func (gr *GraphRunner) runDependencies(ctx context.Context, g *graph.Graph, f executorFunc, opts *runOptions, report *runReport) error {
+	targets := opts.targetSet()
+
+	upstream, downstream, err := opts.dependencyMaps(g)
+	if err != nil {
+		return err
+	}
+
+	var ready []string
+	pending := make(map[string]int, len(upstream))
+	for p, dependencies := range upstream {
+		pending[p] = len(dependencies)
+		if pending[p] == 0 {
+			ready = append(ready, p)
+		}
+	}
+	sort.Strings(ready)
+
+	complete := func(p string) {
+		for _, next := range downstream[p] {
+			pending[next]--
+			if pending[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+		sort.Strings(ready)
+	}
+
+	workers := max(gr.workers, 1)
+	results := make(chan nodeResult)
+	running := 0
+	var errors []error
+
+	for {
+		for len(ready) > 0 && running < workers && (len(errors) == 0 || opts.KeepGoing) && ctx.Err() == nil {
+			p := ready[0]
+			ready = ready[1:]
+
+			// The start node is never run, so it is finished right away
+			if p == g.StartNode.Path() {
+				complete(p)
+				continue
+			}
+
+			n, err := g.Vertex(p)
+			if err != nil {
+				return err
+			}
+
+			if status := skipStatus(n, targets, opts); status != "" {
+				report.set(n, status)
+				complete(p)
+				continue
+			}
+
+			if report.blocked(upstream[p], upstream) {
+				log.Warn().Msgf("Skipping %s because one of its dependencies failed", n.Identifier())
+				report.set(n, StatusSkippedByDependency)
+				complete(p)
+				continue
+			}
+
+			running++
+			go func(ctx context.Context, n graph.Node) {
+				log.Info().Msgf("Running command on %s", n.Identifier())
+
+				out, err := gr.executeWithRetry(ctx, g, f, n, report)
+				if err != nil {
+					report.set(n, failureStatus(err))
+				} else {
+					report.set(n, StatusSucceeded)
+					log.Info().Msg(out)
+				}
+				results <- nodeResult{path: n.Path(), err: err}
+			}(ctx, n)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		r := <-results
+		running--
+		if r.err != nil {
+			errors = append(errors, r.err)
+			if !opts.KeepGoing {
+				continue
+			}
+		} else {
+			log.Info().Msgf("Finished %s", r.path)
+		}
+		complete(r.path)
+	}
+
+	if len(errors) == 0 {
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		log.Info().Msgf("Finished all nodes")
+	}
+
+	return finishRun(report, errors, opts)
+}
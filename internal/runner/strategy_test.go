@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newStrategyTestGraph() *internalgraph.Graph {
+	var nodes = map[string]internalgraph.Node{}
+	for id, typ := range map[string]internalgraph.Type{
+		"main":        internalgraph.ProjectType,
+		"site-1":      internalgraph.SiteType,
+		"component-1": internalgraph.SiteComponentType,
+		"component-2": internalgraph.SiteComponentType,
+		"component-3": internalgraph.SiteComponentType,
+	} {
+		n := newNodeMock(id, id, typ)
+		n.On("Hash").Return(id, nil)
+		nodes[id] = n
+	}
+
+	return internalgraph.CreateGraphMock(
+		nodes,
+		nodes["main"],
+		internalgraph.EdgeMock{Source: "main", Target: "site-1"},
+		internalgraph.EdgeMock{Source: "site-1", Target: "component-1"},
+		internalgraph.EdgeMock{Source: "site-1", Target: "component-2"},
+		internalgraph.EdgeMock{Source: "component-2", Target: "component-3"},
+	)
+}
+
+func TestParseStrategy(t *testing.T) {
+	s, err := ParseStrategy("dependency")
+	assert.NoError(t, err)
+	assert.Equal(t, DependencyStrategy, s)
+
+	_, err = ParseStrategy("unknown")
+	assert.Error(t, err)
+}
+
+func TestGraphRunnerDependencyStrategy(t *testing.T) {
+	runner := GraphRunner{workers: 2, strategy: DependencyStrategy}
+	runner.hash = hash.NewMemoryMapHandler()
+
+	component3Done := make(chan struct{})
+	var mu sync.Mutex
+	var called []string
+
+	err := runner.run(context.Background(), newStrategyTestGraph(), func(ctx context.Context, node internalgraph.Node) (string, error) {
+		mu.Lock()
+		called = append(called, node.Identifier())
+		mu.Unlock()
+
+		switch node.Identifier() {
+		case "component-1":
+			// component-3 does not depend on component-1, so it should be able to finish before component-1 does
+			select {
+			case <-component3Done:
+			case <-time.After(5 * time.Second):
+				return "", errors.New("component-3 did not run while component-1 was running")
+			}
+		case "component-3":
+			close(component3Done)
+		}
+		return "", nil
+	}, &runOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "site-1", called[0])
+	assert.ElementsMatch(t, []string{"site-1", "component-1", "component-2", "component-3"}, called)
+}
+
+func TestGraphRunnerDependencyStrategyError(t *testing.T) {
+	runner := GraphRunner{workers: 1, strategy: DependencyStrategy}
+	runner.hash = hash.NewMemoryMapHandler()
+
+	var called []string
+
+	err := runner.run(context.Background(), newStrategyTestGraph(), func(ctx context.Context, node internalgraph.Node) (string, error) {
+		called = append(called, node.Identifier())
+		if node.Identifier() == "component-2" {
+			return "", assert.AnError
+		}
+		return "", nil
+	}, &runOptions{})
+
+	cliErr := &cli.GroupedError{}
+	assert.ErrorAs(t, err, &cliErr)
+	assert.Len(t, cliErr.Errors, 1)
+	assert.NotContains(t, called, "component-3")
+}
+
+func TestGraphRunnerReverse(t *testing.T) {
+	for _, strategy := range []Strategy{BatchStrategy, DependencyStrategy} {
+		t.Run(string(strategy), func(t *testing.T) {
+			runner := NewGraphRunner(batcher.NaiveBatchFunc(), hash.NewMemoryMapHandler(), 1, strategy)
+
+			var called []string
+			err := runner.run(context.Background(), newStrategyTestGraph(), func(ctx context.Context, node internalgraph.Node) (string, error) {
+				called = append(called, node.Identifier())
+				return "", nil
+			}, &runOptions{Reverse: true})
+
+			assert.NoError(t, err)
+			assert.Len(t, called, 4)
+			index := func(id string) int { return slices.Index(called, id) }
+			assert.Less(t, index("component-3"), index("component-2"))
+			assert.Less(t, index("component-1"), index("site-1"))
+			assert.Less(t, index("component-2"), index("site-1"))
+		})
+	}
+}
+
+func TestGraphRunnerReverseError(t *testing.T) {
+	runner := NewGraphRunner(batcher.NaiveBatchFunc(), hash.NewMemoryMapHandler(), 1, DependencyStrategy)
+
+	var called []string
+	err := runner.run(context.Background(), newStrategyTestGraph(), func(ctx context.Context, node internalgraph.Node) (string, error) {
+		called = append(called, node.Identifier())
+		if node.Identifier() == "component-3" {
+			return "", assert.AnError
+		}
+		return "", nil
+	}, &runOptions{Reverse: true, KeepGoing: true})
+
+	assert.Error(t, err)
+	// The dependencies of a component that failed to be destroyed must not be destroyed
+	assert.NotContains(t, called, "component-2")
+	assert.NotContains(t, called, "site-1")
+	assert.Contains(t, called, "component-1")
+}
+
+func TestGraphRunnerDestroyOrder(t *testing.T) {
+	runner := NewGraphRunner(batcher.NaiveBatchFunc(), hash.NewMemoryMapHandler(), 1, BatchStrategy)
+	g := newStrategyTestGraph()
+
+	var order []string
+	for _, n := range runner.DestroyOrder(g, nil) {
+		order = append(order, n.Identifier())
+	}
+	assert.Equal(t, []string{"component-3", "component-1", "component-2", "site-1"}, order)
+
+	c2, _ := g.Vertex("component-2")
+	c3, _ := g.Vertex("component-3")
+	order = nil
+	for _, n := range runner.DestroyOrder(g, internalgraph.Vertices{c2, c3}) {
+		order = append(order, n.Identifier())
+	}
+	assert.Equal(t, []string{"component-3", "component-2"}, order)
+}
@@ -27,7 +27,8 @@ func determineTainted(oldHash string, n graph.Node, parentTainted bool) (bool, e
 	return h != oldHash, nil
 }
 
-func taintNode(ctx context.Context, hashFetcher hash.Handler, g *graph.Graph, path string, parentTainted bool) error {
+func taintNode(ctx context.Context, hashFetcher hash.Handler, digest outputsDigestFunc, g *graph.Graph, path string,
+	parentTainted bool) error {
 	n, err := g.Vertex(path)
 	if err != nil {
 		return err
@@ -46,6 +47,10 @@ func taintNode(ctx context.Context, hashFetcher hash.Handler, g *graph.Graph, pa
 
 	var isTainted = false
 	if n.Type() != graph.ProjectType {
+		if err = digest(ctx, g, n); err != nil {
+			return err
+		}
+
 		isTainted, err = determineTainted(oldHash, n, parentTainted)
 		if err != nil {
 			return err
@@ -54,7 +59,7 @@ func taintNode(ctx context.Context, hashFetcher hash.Handler, g *graph.Graph, pa
 	n.SetTainted(isTainted)
 
 	for _, child := range am[path] {
-		if err = taintNode(ctx, hashFetcher, g, child.Target, isTainted); err != nil {
+		if err = taintNode(ctx, hashFetcher, digest, g, child.Target, isTainted); err != nil {
 			return err
 		}
 	}
@@ -62,6 +67,8 @@ func taintNode(ctx context.Context, hashFetcher hash.Handler, g *graph.Graph, pa
 	return nil
 }
 
-func taintGraph(ctx context.Context, g *graph.Graph, hashFetcher hash.Handler) error {
-	return taintNode(ctx, hashFetcher, g, g.StartNode.Path(), false)
+// taintGraph marks the nodes that have changed since they were last stored, as well as all their descendants. The
+// digest function determines how the referenced upstream outputs of a node are obtained.
+func taintGraph(ctx context.Context, g *graph.Graph, hashFetcher hash.Handler, digest outputsDigestFunc) error {
+	return taintNode(ctx, hashFetcher, digest, g, g.StartNode.Path(), false)
 }
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mach-composer/mach-composer-cli/internal/graph"
+)
+
+// SetTimeouts sets the maximum duration of a run, and the maximum duration of every terraform command run on a node. A
+// duration of zero disables the timeout. The node timeout can be overridden per component in the configuration.
+func (gr *GraphRunner) SetTimeouts(run, node time.Duration) {
+	gr.runTimeout = run
+	gr.nodeTimeout = node
+}
+
+// withRunTimeout returns a context that is cancelled when the run timeout expires
+func (gr *GraphRunner) withRunTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if gr.runTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, gr.runTimeout,
+		fmt.Errorf("run timed out after %s: %w", gr.runTimeout, context.DeadlineExceeded))
+}
+
+// commandTimeout returns the maximum duration of the terraform commands run on the node. The timeout configured for a
+// component takes precedence over the node timeout of the run. A site that deploys components uses the longest timeout
+// of its components, as a command on the site runs all of them.
+func (gr *GraphRunner) commandTimeout(n graph.Node) time.Duration {
+	components := deployedComponents(n)
+	if len(components) == 0 {
+		return gr.nodeTimeout
+	}
+
+	var timeout time.Duration
+	for _, sc := range components {
+		t := gr.nodeTimeout
+		if sc.SiteComponentConfig.Definition != nil && sc.SiteComponentConfig.Definition.Timeout > 0 {
+			t = sc.SiteComponentConfig.Definition.Timeout
+		}
+		if t <= 0 {
+			// One of the components has no timeout
+			return 0
+		}
+		timeout = max(timeout, t)
+	}
+	return timeout
+}
+
+// failureStatus returns the status of a node that failed with the given error
+func failureStatus(err error) NodeStatus {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return StatusTimedOut
+	}
+	return StatusFailed
+}
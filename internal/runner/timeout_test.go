@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mach-composer/mach-composer-cli/internal/batcher"
+	"github.com/mach-composer/mach-composer-cli/internal/cli"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
+	internalgraph "github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/hash"
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphRunnerNodeTimeout(t *testing.T) {
+	for _, strategy := range []Strategy{BatchStrategy, DependencyStrategy} {
+		t.Run(string(strategy), func(t *testing.T) {
+			runner := GraphRunner{workers: 1, strategy: strategy}
+			runner.hash = hash.NewMemoryMapHandler()
+			runner.batch = batcher.NaiveBatchFunc()
+			runner.SetTimeouts(0, 100*time.Millisecond)
+
+			dir := t.TempDir()
+			var called []string
+
+			err := runner.run(context.Background(), newStrategyTestGraph(), func(ctx context.Context, n internalgraph.Node) (string, error) {
+				called = append(called, n.Identifier())
+				if n.Identifier() == "component-2" {
+					return utils.RunInteractive(ctx, true, "sh", dir, "-c", "exec sleep 10")
+				}
+				return "", nil
+			}, &runOptions{KeepGoing: true})
+
+			cliErr := &cli.GroupedError{}
+			require.ErrorAs(t, err, &cliErr)
+			require.Len(t, cliErr.Errors, 1)
+			assert.ErrorIs(t, cliErr.Errors[0], context.DeadlineExceeded)
+			assert.ElementsMatch(t, []string{"site-1", "component-1", "component-2"}, called)
+		})
+	}
+}
+
+func TestGraphRunnerRunTimeout(t *testing.T) {
+	runner := GraphRunner{workers: 1, strategy: DependencyStrategy}
+	runner.hash = hash.NewMemoryMapHandler()
+	runner.SetTimeouts(100*time.Millisecond, 0)
+
+	dir := t.TempDir()
+	start := time.Now()
+	err := runner.run(context.Background(), newStrategyTestGraph(), func(ctx context.Context, n internalgraph.Node) (string, error) {
+		return utils.RunInteractive(ctx, true, "sh", dir, "-c", "exec sleep 10")
+	}, &runOptions{})
+
+	cliErr := &cli.GroupedError{}
+	require.ErrorAs(t, err, &cliErr)
+	require.Len(t, cliErr.Errors, 1)
+	assert.ErrorIs(t, cliErr.Errors[0], context.DeadlineExceeded)
+	assert.ErrorContains(t, cliErr.Errors[0], "run timed out")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestGraphRunnerCommandTimeout(t *testing.T) {
+	runner := GraphRunner{}
+	runner.SetTimeouts(0, time.Minute)
+
+	n := &internalgraph.SiteComponent{SiteComponentConfig: config.SiteComponentConfig{
+		Definition: &config.ComponentConfig{Timeout: time.Hour},
+	}}
+	assert.Equal(t, time.Hour, runner.commandTimeout(n))
+
+	n.SiteComponentConfig.Definition.Timeout = 0
+	assert.Equal(t, time.Minute, runner.commandTimeout(n))
+
+	// A site uses the longest timeout of the components deployed as part of it
+	site := newSiteTestGraph(t, &config.ComponentConfig{Name: "api", Timeout: time.Hour}, &config.ComponentConfig{Name: "payment"})
+	assert.Equal(t, time.Hour, runner.commandTimeout(site))
+
+	site = newSiteTestGraph(t, &config.ComponentConfig{Name: "api", Timeout: time.Second}, &config.ComponentConfig{Name: "payment"})
+	assert.Equal(t, time.Minute, runner.commandTimeout(site))
+
+	runner.nodeTimeout = 0
+	assert.Equal(t, time.Duration(0), runner.commandTimeout(site))
+}
+
+func TestFailureStatus(t *testing.T) {
+	assert.Equal(t, StatusFailed, failureStatus(assert.AnError))
+	assert.Equal(t, StatusTimedOut, failureStatus(context.DeadlineExceeded))
+}
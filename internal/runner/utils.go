@@ -3,7 +3,6 @@ package runner
 import (
 	"context"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
-	"github.com/mach-composer/mach-composer-cli/internal/utils"
 	"github.com/rs/zerolog/log"
 	"os"
 	"path/filepath"
@@ -32,7 +31,7 @@ func terraformCanPlan(ctx context.Context, n graph.Node) (bool, error) {
 	}
 
 	for _, parent := range parents {
-		v, err := utils.GetTerraformOutputs(ctx, parent.Path())
+		v, err := cachedOutput(ctx, parent.Path())
 		if err != nil {
 			return false, nil
 		}
@@ -43,3 +42,15 @@ func terraformCanPlan(ctx context.Context, n graph.Node) (bool, error) {
 	}
 	return true, nil
 }
+
+// deployedComponents returns the components of which the node manages the resources. These are the nested components
+// of a site, or the component itself if it is deployed separately.
+func deployedComponents(n graph.Node) []*graph.SiteComponent {
+	switch v := n.(type) {
+	case *graph.Site:
+		return v.NestedNodes
+	case *graph.SiteComponent:
+		return []*graph.SiteComponent{v}
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package runner
 
 import (
 	"context"
+	"github.com/mach-composer/mach-composer-cli/internal/config"
 	"github.com/mach-composer/mach-composer-cli/internal/graph"
+	"github.com/mach-composer/mach-composer-cli/internal/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"os"
 	"path"
 	"testing"
@@ -44,7 +47,10 @@ func TestTerraformCanPlanWithComponentParentEmptyOutput(t *testing.T) {
 	n.On("Parents").Return([]graph.Node{p}, nil).Once()
 	n.On("Type").Return(graph.SiteComponentType).Once()
 
-	canPlan, err := terraformCanPlan(context.Background(), n)
+	ctx := terraform.WithExecutor(context.Background(), &terraform.RecordingExecutor{
+		Respond: func(terraform.RecordedCommand) (string, error) { return "{}", nil },
+	})
+	canPlan, err := terraformCanPlan(ctx, n)
 	assert.NoError(t, err)
 	assert.False(t, canPlan)
 }
@@ -59,7 +65,38 @@ func TestTerraformCanPlanWithParentOutput(t *testing.T) {
 	n.On("Parents").Return([]graph.Node{p}, nil).Once()
 	n.On("Type").Return(graph.SiteComponentType).Once()
 
-	canPlan, err := terraformCanPlan(context.Background(), n)
+	recorder := &terraform.RecordingExecutor{
+		Respond: func(terraform.RecordedCommand) (string, error) {
+			return `{"some-output":{"sensitive":false,"type":"string","value":"hello-world"}}`, nil
+		},
+	}
+	canPlan, err := terraformCanPlan(terraform.WithExecutor(context.Background(), recorder), n)
 	assert.NoError(t, err)
 	assert.True(t, canPlan)
+	assert.Equal(t, []terraform.RecordedCommand{
+		{Path: path.Join(dir, "testdata/initialized"), Args: []string{"output", "-json"}},
+	}, recorder.Commands())
+}
+
+// newSiteTestGraph creates a deployment graph with a single site that deploys the given components as part of the site,
+// and returns the site node
+func newSiteTestGraph(t *testing.T, components ...*config.ComponentConfig) *graph.Site {
+	var siteComponents []config.SiteComponentConfig
+	for _, c := range components {
+		siteComponents = append(siteComponents, config.SiteComponentConfig{Name: c.Name, Definition: c})
+	}
+
+	g := newTestGraph(t, t.TempDir(), config.DeploymentSite, siteComponents...)
+	return testNode(t, g, graph.SiteType).(*graph.Site)
+}
+
+func TestDeployedComponents(t *testing.T) {
+	site := newSiteTestGraph(t, &config.ComponentConfig{Name: "api"}, &config.ComponentConfig{Name: "payment"})
+
+	components := deployedComponents(site)
+	require.Len(t, components, 2)
+	assert.ElementsMatch(t, []string{"api", "payment"}, []string{
+		components[0].SiteComponentConfig.Name, components[1].SiteComponentConfig.Name,
+	})
+	assert.Equal(t, []*graph.SiteComponent{components[0]}, deployedComponents(components[0]))
 }
@@ -2,7 +2,6 @@ package terraform
 
 import (
 	"context"
-	"github.com/mach-composer/mach-composer-cli/internal/utils"
 	"strings"
 )
 
@@ -17,14 +16,15 @@ func Apply(ctx context.Context, path string, destroy, autoApprove bool) (string,
 		cmd = append(cmd, "-auto-approve")
 	}
 
-	// If there is a plan then we should use it.
+	// If there is a plan then we should use it. Saved plans are never destroy plans, so these are ignored when
+	// destroying.
 	planFilename, err := hasTerraformPlan(path)
 	if err != nil {
 		return "", err
 	}
-	if planFilename != "" {
+	if planFilename != "" && !destroy {
 		cmd = append(cmd, strings.TrimPrefix(planFilename, path+"/"))
 	}
 
-	return utils.RunTerraform(ctx, path, false, cmd...)
+	return Run(ctx, path, false, cmd...)
 }
@@ -0,0 +1,30 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+)
+
+// driftExitCode is the exit code of terraform plan with -detailed-exitcode when there are changes
+const driftExitCode = 2
+
+// DetectDrift plans the node without saving the plan, and returns true if there are changes. With refreshOnly only
+// changes made outside of terraform are considered. The state is not locked, as it is not modified.
+func DetectDrift(ctx context.Context, path string, refreshOnly bool) (bool, error) {
+	cmd := []string{"plan", "-detailed-exitcode", "-input=false", "-lock=false"}
+	if refreshOnly {
+		cmd = append(cmd, "-refresh-only")
+	}
+
+	_, err := Run(ctx, path, false, cmd...)
+	if err != nil {
+		// Both exec.ExitError and ExitError report the exit code
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == driftExitCode {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return false, nil
+}
@@ -0,0 +1,42 @@
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exitWith returns a context in which every terraform command exits with the given code
+func exitWith(code int) (context.Context, *RecordingExecutor) {
+	recorder := &RecordingExecutor{Respond: func(RecordedCommand) (string, error) {
+		if code == 0 {
+			return "", nil
+		}
+		return "", &ExitError{Code: code}
+	}}
+	return WithExecutor(context.Background(), recorder), recorder
+}
+
+func TestDetectDrift(t *testing.T) {
+	dir := t.TempDir()
+
+	ctx, recorder := exitWith(0)
+	drift, err := DetectDrift(ctx, dir, true)
+	require.NoError(t, err)
+	assert.False(t, drift)
+	assert.Equal(t, []RecordedCommand{{
+		Path: dir,
+		Args: []string{"plan", "-detailed-exitcode", "-input=false", "-lock=false", "-refresh-only"},
+	}}, recorder.Commands())
+
+	ctx, _ = exitWith(2)
+	drift, err = DetectDrift(ctx, dir, true)
+	require.NoError(t, err)
+	assert.True(t, drift)
+
+	ctx, _ = exitWith(1)
+	_, err = DetectDrift(ctx, dir, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mach-composer/mach-composer-cli/internal/utils"
+)
+
+// Executor runs terraform commands in the given working directory. If catchOutputs is set the standard output is
+// returned instead of written.
+type Executor interface {
+	Run(ctx context.Context, path string, catchOutputs bool, args ...string) (string, error)
+}
+
+type executorKey struct{}
+
+// WithExecutor returns a context in which the terraform commands are run by the given executor
+func WithExecutor(ctx context.Context, e Executor) context.Context {
+	return context.WithValue(ctx, executorKey{}, e)
+}
+
+// commandExecutor runs the commands using the configured terraform executable
+type commandExecutor struct{}
+
+func (commandExecutor) Run(ctx context.Context, path string, catchOutputs bool, args ...string) (string, error) {
+	return utils.RunTerraform(ctx, path, catchOutputs, args...)
+}
+
+// Run runs a terraform command using the executor of the context, which defaults to running the terraform executable
+func Run(ctx context.Context, path string, catchOutputs bool, args ...string) (string, error) {
+	e, ok := ctx.Value(executorKey{}).(Executor)
+	if !ok {
+		e = commandExecutor{}
+	}
+	return e.Run(ctx, path, catchOutputs, args...)
+}
+
+// RecordedCommand is a terraform command recorded by the RecordingExecutor
+type RecordedCommand struct {
+	Path string
+	Args []string
+}
+
+func (c RecordedCommand) String() string {
+	return fmt.Sprintf("cd %s && %s %s", c.Path, filepath.Base(utils.TerraformBinary()), strings.Join(c.Args, " "))
+}
+
+// ExitError can be returned by the Respond function of a RecordingExecutor to simulate a command that exits with a
+// non-zero exit code
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// RecordingExecutor records the commands instead of running them. It is used to preview a run, and to test without
+// terraform installed. It is safe for concurrent use.
+type RecordingExecutor struct {
+	mu       sync.Mutex
+	commands []RecordedCommand
+	// Respond returns the result of a recorded command. If not set every command succeeds without output.
+	Respond func(c RecordedCommand) (string, error)
+}
+
+func (e *RecordingExecutor) Run(_ context.Context, path string, _ bool, args ...string) (string, error) {
+	c := RecordedCommand{Path: path, Args: args}
+
+	e.mu.Lock()
+	e.commands = append(e.commands, c)
+	e.mu.Unlock()
+
+	if e.Respond == nil {
+		return "", nil
+	}
+	return e.Respond(c)
+}
+
+// Commands returns the recorded commands in the order they were run
+func (e *RecordingExecutor) Commands() []RecordedCommand {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]RecordedCommand(nil), e.commands...)
+}
@@ -0,0 +1,37 @@
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingExecutor(t *testing.T) {
+	recorder := &RecordingExecutor{}
+	ctx := WithExecutor(context.Background(), recorder)
+
+	_, err := Init(ctx, "deployments/main/site-1")
+	require.NoError(t, err)
+	_, err = Plan(ctx, "deployments/main/site-1", false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []RecordedCommand{
+		{Path: "deployments/main/site-1", Args: []string{"init"}},
+		{Path: "deployments/main/site-1", Args: []string{"plan", "-lock=false", "-out=terraform.plan"}},
+	}, recorder.Commands())
+	assert.Equal(t, "cd deployments/main/site-1 && terraform init", recorder.Commands()[0].String())
+}
+
+func TestRecordingExecutorRespond(t *testing.T) {
+	ctx := WithExecutor(context.Background(), &RecordingExecutor{
+		Respond: func(c RecordedCommand) (string, error) {
+			return `{"endpoint":{"sensitive":false,"type":"string","value":"https://example.org"}}`, nil
+		},
+	})
+
+	v, err := Output(ctx, "deployments/main/site-1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", v.GetAttr("endpoint").GetAttr("value").AsString())
+}
@@ -2,11 +2,10 @@ package terraform
 
 import (
 	"context"
-	"github.com/mach-composer/mach-composer-cli/internal/utils"
 )
 
 func Init(ctx context.Context, path string) (string, error) {
 	args := []string{"init"}
 
-	return utils.RunTerraform(ctx, path, false, args...)
+	return Run(ctx, path, false, args...)
 }
@@ -0,0 +1,24 @@
+package terraform
+
+import (
+	"regexp"
+	"strings"
+)
+
+var lockIDPattern = regexp.MustCompile(`Lock Info:\s+ID:\s+(\S+)`)
+
+// LockReleaseFailed returns true if the output of a terraform command reports that the state lock could not be
+// released
+func LockReleaseFailed(output string) bool {
+	return strings.Contains(output, "Error releasing the state lock")
+}
+
+// ParseLockID returns the ID of the last state lock reported in the output of a terraform command, or an empty string
+// if no lock is reported
+func ParseLockID(output string) string {
+	matches := lockIDPattern.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[len(matches)-1][1]
+}
@@ -0,0 +1,29 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const lockReleaseOutput = `
+Error releasing the state lock
+
+Error message: failed to retrieve lock info: RequestError: send request failed
+
+Terraform acquired a lock on the state for you, but encountered an error when attempting to unlock it.
+Lock Info:
+  ID:        0f9a8c1e-3c1b-4b9e-9a66-cbc5a0d1f2e3
+  Path:      terraform-state/site-1/terraform.tfstate
+  Operation: OperationTypeApply
+`
+
+func TestParseLockID(t *testing.T) {
+	assert.Equal(t, "0f9a8c1e-3c1b-4b9e-9a66-cbc5a0d1f2e3", ParseLockID(lockReleaseOutput))
+	assert.Equal(t, "", ParseLockID("Apply complete! Resources: 0 added, 0 changed, 0 destroyed."))
+}
+
+func TestLockReleaseFailed(t *testing.T) {
+	assert.True(t, LockReleaseFailed(lockReleaseOutput))
+	assert.False(t, LockReleaseFailed("Error: Error acquiring the state lock"))
+}
@@ -0,0 +1,89 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// modulesManifest is the manifest in which terraform init records where the modules are installed
+type modulesManifest struct {
+	Modules []struct {
+		Key string `json:"Key"`
+		Dir string `json:"Dir"`
+	} `json:"Modules"`
+}
+
+var moduleOutputsSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "output", LabelNames: []string{"name"}}},
+}
+
+var outputSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "sensitive"}},
+}
+
+// SensitiveModuleOutputs returns the names of the outputs of the module with the given name that are marked as
+// sensitive. The module is read from the directory it was installed in by terraform init, so terraform must be
+// initialized at the given path. Outputs of which the sensitivity cannot be determined are considered sensitive.
+func SensitiveModuleOutputs(path, module string) (map[string]bool, error) {
+	dir, err := moduleDir(path, module)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	result := map[string]bool{}
+	for _, filename := range files {
+		f, diags := parser.ParseHCLFile(filename)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse %s: %w", filename, diags)
+		}
+
+		content, _, _ := f.Body.PartialContent(moduleOutputsSchema)
+		for _, block := range content.Blocks {
+			attrs, _, _ := block.Body.PartialContent(outputSchema)
+			attr, ok := attrs.Attributes["sensitive"]
+			if !ok {
+				continue
+			}
+
+			v, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || v.IsNull() || !v.IsKnown() || v.Type() != cty.Bool || v.True() {
+				result[block.Labels[0]] = true
+			}
+		}
+	}
+	return result, nil
+}
+
+func moduleDir(path, module string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(path, ".terraform", "modules", "modules.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read modules manifest: %w", err)
+	}
+
+	var manifest modulesManifest
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return "", fmt.Errorf("failed to parse modules manifest: %w", err)
+	}
+
+	for _, m := range manifest.Modules {
+		if m.Key == module {
+			if filepath.IsAbs(m.Dir) {
+				return m.Dir, nil
+			}
+			return filepath.Join(path, m.Dir), nil
+		}
+	}
+	return "", fmt.Errorf("module %s is not installed in %s", module, path)
+}
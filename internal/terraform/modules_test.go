@@ -0,0 +1,51 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensitiveModuleOutputs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".terraform", "modules", "payment"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform", "modules", "modules.json"), []byte(`{
+		"Modules": [
+			{"Key": "", "Source": "", "Dir": "."},
+			{"Key": "payment", "Source": "git::https://example.com/payment.git", "Dir": ".terraform/modules/payment"}
+		]
+	}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform", "modules", "payment", "outputs.tf"), []byte(`
+output "endpoint" {
+  value = "https://payment.example.com"
+}
+
+output "api_key" {
+  value     = "secret"
+  sensitive = true
+}
+
+output "token" {
+  value     = "secret"
+  sensitive = var.sensitive
+}
+
+output "ports" {
+  value     = [80, 443]
+  sensitive = false
+}
+`), 0644))
+
+	outputs, err := SensitiveModuleOutputs(dir, "payment")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"api_key": true, "token": true}, outputs)
+
+	_, err = SensitiveModuleOutputs(dir, "search")
+	assert.ErrorContains(t, err, "module search is not installed")
+
+	_, err = SensitiveModuleOutputs(t.TempDir(), "payment")
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+package terraform
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Output returns the outputs in the terraform state at the given path
+func Output(ctx context.Context, path string) (cty.Value, error) {
+	var data ctyjson.SimpleJSONValue
+
+	output, err := Run(ctx, path, true, "output", "-json")
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to get terraform output: %s", err.Error())
+		return cty.NilVal, err
+	}
+
+	log.Debug().Str("output", output).Msgf("Fetched terraform output")
+
+	if err = data.UnmarshalJSON([]byte(output)); err != nil {
+		log.Error().Err(err).Str("output", output).Msgf("failed to unmarshal terraform output: %s", err.Error())
+		return cty.NilVal, err
+	}
+
+	return data.Value, nil
+}
@@ -3,7 +3,6 @@ package terraform
 import (
 	"context"
 	"fmt"
-	"github.com/mach-composer/mach-composer-cli/internal/utils"
 )
 
 func Plan(ctx context.Context, path string, lock bool) (string, error) {
@@ -14,5 +13,5 @@ func Plan(ctx context.Context, path string, lock bool) (string, error) {
 	}
 
 	cmd = append(cmd, fmt.Sprintf("-out=%s", PlanFile))
-	return utils.RunTerraform(ctx, path, false, cmd...)
+	return Run(ctx, path, false, cmd...)
 }
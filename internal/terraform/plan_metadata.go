@@ -0,0 +1,86 @@
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PlanMetadataFile is the sidecar file of the plan, recording the configuration the plan was made for
+const PlanMetadataFile = PlanFile + ".json"
+
+// PlanMetadata records the state of the configuration at the time a plan was made. It is used to detect plans that
+// no longer match the configuration.
+type PlanMetadata struct {
+	// Hash is the hash of the node
+	Hash string `json:"hash"`
+	// GeneratedFileDigest is the digest of the generated terraform file
+	GeneratedFileDigest string `json:"generated_file_digest"`
+}
+
+// NewPlanMetadata creates the metadata for a plan of the node at the given path with the given node hash
+func NewPlanMetadata(path, hash string) (*PlanMetadata, error) {
+	digest, err := generatedFileDigest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PlanMetadata{Hash: hash, GeneratedFileDigest: digest}, nil
+}
+
+func generatedFileDigest(path string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(path, "main.tf"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read generated file: %w", err)
+	}
+
+	digest := sha256.Sum256(content)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// WritePlanMetadata writes the sidecar of the plan
+func WritePlanMetadata(path string, m *PlanMetadata) error {
+	c, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(path, PlanMetadataFile), c, 0600)
+}
+
+// ReadPlanMetadata reads the sidecar of the plan. If there is no sidecar nil is returned
+func ReadPlanMetadata(path string) (*PlanMetadata, error) {
+	c, err := os.ReadFile(filepath.Join(path, PlanMetadataFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	m := &PlanMetadata{}
+	if err = json.Unmarshal(c, m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", PlanMetadataFile, err)
+	}
+	return m, nil
+}
+
+// HasPlan returns true if a plan exists for the node at the given path
+func HasPlan(path string) (bool, error) {
+	filename, err := hasTerraformPlan(path)
+	return filename != "", err
+}
+
+// RemovePlan removes the plan and its sidecar, if they exist
+func RemovePlan(path string) error {
+	for _, f := range []string{PlanFile, PlanMetadataFile} {
+		if err := os.Remove(filepath.Join(path, f)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanMetadata(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`provider "aws" {}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, PlanFile), []byte("plan"), 0600))
+
+	m, err := ReadPlanMetadata(dir)
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	m, err = NewPlanMetadata(dir, "hash")
+	require.NoError(t, err)
+	require.NoError(t, WritePlanMetadata(dir, m))
+
+	read, err := ReadPlanMetadata(dir)
+	require.NoError(t, err)
+	assert.Equal(t, m, read)
+
+	// A change in the generated file results in a different digest
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`provider "google" {}`), 0600))
+	changed, err := NewPlanMetadata(dir, "hash")
+	require.NoError(t, err)
+	assert.NotEqual(t, m.GeneratedFileDigest, changed.GeneratedFileDigest)
+
+	require.NoError(t, RemovePlan(dir))
+	ok, err := HasPlan(dir)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, err = os.Stat(filepath.Join(dir, PlanMetadataFile))
+	assert.True(t, os.IsNotExist(err))
+
+	// Removing a plan that does not exist is not an error
+	assert.NoError(t, RemovePlan(dir))
+}
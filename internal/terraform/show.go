@@ -3,10 +3,18 @@ package terraform
 import (
 	"context"
 	"fmt"
-	"github.com/mach-composer/mach-composer-cli/internal/utils"
 )
 
 func Show(ctx context.Context, path string, noColor bool) (string, error) {
+	return show(ctx, path, false, noColor)
+}
+
+// ShowJSON returns the plan in the machine-readable JSON format
+func ShowJSON(ctx context.Context, path string) (string, error) {
+	return show(ctx, path, true, false)
+}
+
+func show(ctx context.Context, path string, json, noColor bool) (string, error) {
 	filename, err := hasTerraformPlan(path)
 	if err != nil {
 		return "", err
@@ -15,9 +23,13 @@ func Show(ctx context.Context, path string, noColor bool) (string, error) {
 		return "", fmt.Errorf("no plan found for path %s. Did you run `mach-composer plan`", path)
 	}
 
-	cmd := []string{"show", filename}
+	cmd := []string{"show"}
+	if json {
+		cmd = append(cmd, "-json")
+	}
 	if noColor {
 		cmd = append(cmd, "-no-color")
 	}
-	return utils.RunTerraform(ctx, path, false, cmd...)
+	cmd = append(cmd, filename)
+	return Run(ctx, path, json, cmd...)
 }
@@ -0,0 +1,62 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Import imports an existing resource with the given id into the state at the given address
+func Import(ctx context.Context, path, address, id string) (string, error) {
+	return Run(ctx, path, false, "import", address, id)
+}
+
+// StateMove moves a resource within the state. With dryRun the resources that would be moved are only listed.
+func StateMove(ctx context.Context, path, source, destination string, dryRun bool) (string, error) {
+	cmd := []string{"state", "mv"}
+	if dryRun {
+		cmd = append(cmd, "-dry-run")
+	}
+
+	cmd = append(cmd, source, destination)
+	return Run(ctx, path, false, cmd...)
+}
+
+// StateRemove removes resources from the state, without destroying them. With dryRun the resources that would be
+// removed are only listed.
+func StateRemove(ctx context.Context, path string, addresses []string, dryRun bool) (string, error) {
+	cmd := []string{"state", "rm"}
+	if dryRun {
+		cmd = append(cmd, "-dry-run")
+	}
+
+	cmd = append(cmd, addresses...)
+	return Run(ctx, path, false, cmd...)
+}
+
+// StatePull returns the current state of the node at the given path
+func StatePull(ctx context.Context, path string) (string, error) {
+	return Run(ctx, path, true, "state", "pull")
+}
+
+// StatePush replaces the state of the node at the given path with the given state file
+func StatePush(ctx context.Context, path, stateFile string) (string, error) {
+	return Run(ctx, path, false, "state", "push", stateFile)
+}
+
+// StateList lists the resources in the local state file that match the address
+func StateList(ctx context.Context, path, stateFile, address string) ([]string, error) {
+	out, err := Run(ctx, path, true, "state", "list", fmt.Sprintf("-state=%s", stateFile), address)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(out), nil
+}
+
+// StateMoveToFile moves resources from a local state file to another local state file, which is created if it does not
+// exist yet
+func StateMoveToFile(ctx context.Context, path, stateFile, stateOut, source, destination string) (string, error) {
+	return Run(ctx, path, false, "state", "mv", fmt.Sprintf("-state=%s", stateFile),
+		fmt.Sprintf("-state-out=%s", stateOut), source, destination)
+}
@@ -0,0 +1,115 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ResourceChanges contains the addresses of the resources with the same planned action
+type ResourceChanges struct {
+	Count     int      `json:"count"`
+	Addresses []string `json:"addresses"`
+}
+
+func (r *ResourceChanges) add(address string) {
+	r.Count++
+	r.Addresses = append(r.Addresses, address)
+}
+
+// PlanSummary is a summary of the resource changes in a plan, grouped by action
+type PlanSummary struct {
+	Create  ResourceChanges `json:"create"`
+	Update  ResourceChanges `json:"update"`
+	Delete  ResourceChanges `json:"delete"`
+	Replace ResourceChanges `json:"replace"`
+}
+
+// HasChanges returns true if the plan contains any resource changes
+func (s *PlanSummary) HasChanges() bool {
+	return s.Create.Count+s.Update.Count+s.Delete.Count+s.Replace.Count > 0
+}
+
+// plan is the subset of the `terraform show -json` output that is needed for the summary. See
+// https://developer.hashicorp.com/terraform/internals/json-format#plan-representation
+type plan struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Type    string `json:"type"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+func parsePlan(data []byte) (*plan, error) {
+	var p plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	return &p, nil
+}
+
+// ParsePlanSummary parses the output of `terraform show -json` for a plan file
+func ParsePlanSummary(data []byte) (*PlanSummary, error) {
+	p, err := parsePlan(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PlanSummary{
+		Create:  ResourceChanges{Addresses: []string{}},
+		Update:  ResourceChanges{Addresses: []string{}},
+		Delete:  ResourceChanges{Addresses: []string{}},
+		Replace: ResourceChanges{Addresses: []string{}},
+	}
+
+	for _, rc := range p.ResourceChanges {
+		actions := rc.Change.Actions
+		switch {
+		case len(actions) == 2:
+			// Either ["delete", "create"] or ["create", "delete"], depending on create_before_destroy
+			s.Replace.add(rc.Address)
+		case len(actions) == 1 && actions[0] == "create":
+			s.Create.add(rc.Address)
+		case len(actions) == 1 && actions[0] == "update":
+			s.Update.add(rc.Address)
+		case len(actions) == 1 && actions[0] == "delete":
+			s.Delete.add(rc.Address)
+		}
+	}
+
+	for _, r := range []*ResourceChanges{&s.Create, &s.Update, &s.Delete, &s.Replace} {
+		sort.Strings(r.Addresses)
+	}
+
+	return s, nil
+}
+
+// Resource identifies a resource in a plan
+type Resource struct {
+	Address string
+	Type    string
+}
+
+// ParseDestroyedResources parses the output of `terraform show -json` for a plan file, and returns the resources that
+// are deleted or replaced by the plan, ordered by address
+func ParseDestroyedResources(data []byte) ([]Resource, error) {
+	p, err := parsePlan(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, rc := range p.ResourceChanges {
+		actions := rc.Change.Actions
+		if len(actions) == 2 || (len(actions) == 1 && actions[0] == "delete") {
+			resources = append(resources, Resource{Address: rc.Address, Type: rc.Type})
+		}
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Address < resources[j].Address
+	})
+	return resources, nil
+}
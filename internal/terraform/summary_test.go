@@ -0,0 +1,63 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlanSummary(t *testing.T) {
+	data := []byte(`{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "module.api.aws_s3_bucket.b", "change": {"actions": ["create"]}},
+    {"address": "module.api.aws_s3_bucket.a", "change": {"actions": ["create"]}},
+    {"address": "module.api.aws_lambda_function.main", "change": {"actions": ["update"]}},
+    {"address": "module.api.aws_iam_role.old", "change": {"actions": ["delete"]}},
+    {"address": "module.api.aws_route53_record.main", "change": {"actions": ["delete", "create"]}},
+    {"address": "module.api.aws_acm_certificate.main", "change": {"actions": ["create", "delete"]}},
+    {"address": "module.api.aws_s3_bucket.unchanged", "change": {"actions": ["no-op"]}},
+    {"address": "module.api.data.aws_caller_identity.current", "change": {"actions": ["read"]}}
+  ]
+}`)
+
+	s, err := ParsePlanSummary(data)
+	require.NoError(t, err)
+
+	assert.True(t, s.HasChanges())
+	assert.Equal(t, ResourceChanges{Count: 2, Addresses: []string{
+		"module.api.aws_s3_bucket.a", "module.api.aws_s3_bucket.b",
+	}}, s.Create)
+	assert.Equal(t, ResourceChanges{Count: 1, Addresses: []string{"module.api.aws_lambda_function.main"}}, s.Update)
+	assert.Equal(t, ResourceChanges{Count: 1, Addresses: []string{"module.api.aws_iam_role.old"}}, s.Delete)
+	assert.Equal(t, ResourceChanges{Count: 2, Addresses: []string{
+		"module.api.aws_acm_certificate.main", "module.api.aws_route53_record.main",
+	}}, s.Replace)
+}
+
+func TestParsePlanSummaryNoChanges(t *testing.T) {
+	s, err := ParsePlanSummary([]byte(`{"format_version": "1.2"}`))
+	require.NoError(t, err)
+	assert.False(t, s.HasChanges())
+	assert.Equal(t, []string{}, s.Create.Addresses)
+}
+
+func TestParseDestroyedResources(t *testing.T) {
+	data := []byte(`{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "module.api.aws_s3_bucket.b", "type": "aws_s3_bucket", "change": {"actions": ["create"]}},
+    {"address": "module.api.aws_iam_role.old", "type": "aws_iam_role", "change": {"actions": ["delete"]}},
+    {"address": "module.api.aws_route53_record.main", "type": "aws_route53_record", "change": {"actions": ["delete", "create"]}},
+    {"address": "module.api.aws_lambda_function.main", "type": "aws_lambda_function", "change": {"actions": ["update"]}}
+  ]
+}`)
+
+	resources, err := ParseDestroyedResources(data)
+	require.NoError(t, err)
+	assert.Equal(t, []Resource{
+		{Address: "module.api.aws_iam_role.old", Type: "aws_iam_role"},
+		{Address: "module.api.aws_route53_record.main", Type: "aws_route53_record"},
+	}, resources)
+}
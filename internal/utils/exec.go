@@ -3,15 +3,92 @@ package utils
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
+// DefaultStopGracePeriod is the default time a process is given to exit after it is interrupted
+const DefaultStopGracePeriod = 30 * time.Second
+
+var stopGracePeriod = DefaultStopGracePeriod
+
+var (
+	// ErrInterrupted is the cause of the context cancellation when the user interrupts mach-composer
+	ErrInterrupted = errors.New("interrupted")
+	// ErrProcessKilled is returned when a process did not exit within the grace period after being interrupted. A
+	// terraform process that is killed might not have released its state lock.
+	ErrProcessKilled = errors.New("process was killed because it did not exit within the grace period")
+)
+
+// SetStopGracePeriod sets the time a process is given to exit after it is interrupted before it is killed
+func SetStopGracePeriod(d time.Duration) {
+	stopGracePeriod = d
+}
+
+type commandOutputKey struct{}
+
+type commandTimeoutKey struct{}
+
+// CommandOutput defines where the input and output of the commands run by RunInteractive are connected to
+type CommandOutput struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// LogDir is the directory in which a log file with the full output of every command is written. No log files
+	// are written if it is empty
+	LogDir string
+}
+
+// WithCommandOutput returns a context in which the commands run by RunInteractive use the given output
+func WithCommandOutput(ctx context.Context, o *CommandOutput) context.Context {
+	return context.WithValue(ctx, commandOutputKey{}, o)
+}
+
+func commandOutputFromContext(ctx context.Context) *CommandOutput {
+	if o, ok := ctx.Value(commandOutputKey{}).(*CommandOutput); ok {
+		return o
+	}
+	return &CommandOutput{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+// WithCommandTimeout returns a context in which every command run by RunInteractive is stopped if it does not finish
+// within the given duration. A duration of zero disables the timeout.
+func WithCommandTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, commandTimeoutKey{}, d)
+}
+
+// createLogFile creates the log file of a command, named after the (sub)command and the current time
+func createLogFile(dir string, command string, args []string) (*os.File, error) {
+	name := filepath.Base(command)
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("%s-%s.log", name, time.Now().Format("20060102T150405")))
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = fmt.Fprintf(f, "$ %s %s\n", filepath.Base(command), strings.Join(args, " ")); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
 func RunInteractive(ctx context.Context, catchOutputs bool, command string, cwd string, args ...string) (string, error) {
 	logger := log.Ctx(ctx).With().
 		Str("command", command).
@@ -20,17 +97,41 @@ func RunInteractive(ctx context.Context, catchOutputs bool, command string, cwd
 
 	logger.Debug().Msgf("Running: %s", command)
 
-	cmd := exec.CommandContext(ctx, command, args...)
+	if timeout, ok := ctx.Value(commandTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout,
+			fmt.Errorf("command timed out after %s: %w", timeout, context.DeadlineExceeded))
+		defer cancel()
+	}
+
+	// The process is stopped by StopProcess when the context is done, so it is not bound to the context itself
+	cmd := exec.Command(command, args...)
 	cmd.Dir = cwd
 	cmd.Env = os.Environ()
 
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
+	output := commandOutputFromContext(ctx)
+	cmd.Stdin = output.Stdin
+	if output.Stdin == nil {
+		// Without input the process is not attached to the terminal, so it is only interrupted once through the
+		// context and never directly by the terminal
+		setProcessGroup(cmd)
+	}
+	cmd.Stderr = output.Stderr
+	cmd.Stdout = output.Stdout
 
 	stdOut := new(bytes.Buffer)
 	if catchOutputs {
 		cmd.Stdout = stdOut
+	} else if output.LogDir != "" {
+		// Caught outputs are returned to the caller and might contain sensitive values, so these are not logged
+		logFile, err := createLogFile(output.LogDir, command, args)
+		if err != nil {
+			return "", fmt.Errorf("failed to create log file: %w", err)
+		}
+		defer logFile.Close()
+
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, logFile)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, logFile)
 	}
 
 	err := cmd.Start()
@@ -39,14 +140,23 @@ func RunInteractive(ctx context.Context, catchOutputs bool, command string, cwd
 	}
 
 	// Wait for the command to complete or the context to be cancelled
-	done := make(chan error)
+	done := make(chan error, 1)
 	go func() {
 		done <- cmd.Wait()
 	}()
 
 	select {
 	case <-ctx.Done():
-		return "", StopProcess(cmd)
+		// When interrupted from the terminal, the interactive process has received the signal as well. A second
+		// interrupt would make terraform exit immediately without releasing the state lock.
+		interrupt := !(cmd.Stdin != nil && errors.Is(context.Cause(ctx), ErrInterrupted) && isTerminal(os.Stdin))
+
+		if err := StopProcess(cmd, done, interrupt); err != nil {
+			return "", fmt.Errorf("command (%s) was stopped: %w: %w (args: %s , cwd: %s)", command, context.Cause(ctx),
+				err, strings.Join(args, " "), cwd)
+		}
+		return "", fmt.Errorf("command (%s) was stopped: %w (args: %s , cwd: %s)", command, context.Cause(ctx),
+			strings.Join(args, " "), cwd)
 
 	case err := <-done:
 		if err != nil {
@@ -59,35 +169,33 @@ func RunInteractive(ctx context.Context, catchOutputs bool, command string, cwd
 	return stdOut.String(), nil
 }
 
-func StopProcess(cmd *exec.Cmd) error {
-	log.Info().Msg("Context cancelled, waiting for the command to exit...")
-	err := cmd.Process.Signal(os.Interrupt)
-	if err != nil {
-		return fmt.Errorf("failed to send interrupt signal: %w\n", err)
+// StopProcess interrupts the process, so terraform can finish its current operation and release the state lock, and
+// waits for it to exit. If the process does not exit within the grace period it is killed, and ErrProcessKilled is
+// returned. The interrupt is not sent if the process already received it, for example from the terminal.
+func StopProcess(cmd *exec.Cmd, done <-chan error, interrupt bool) error {
+	if interrupt {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			// Interrupts are not supported on all platforms, in which case the process can only be killed
+			log.Warn().Err(err).Msgf("Failed to interrupt process %d, killing it", cmd.Process.Pid)
+			return killProcess(cmd, done)
+		}
 	}
 
-	// Poll for the process to exit
-	pollInterval := 250 * time.Millisecond
-	ticker := time.NewTicker(pollInterval)
-	current := time.Now()
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			log.Info().Msg("Waiting...")
-			log.Info().Msg(cmd.ProcessState.String())
-			if cmd.ProcessState != nil && cmd.ProcessState.Exited() {
-				log.Info().Msg("Command exited.")
-				return nil
-			}
-			if current.Add(10 * time.Second).After(time.Now()) {
-				err := cmd.Process.Signal(os.Kill)
-				if err != nil {
-					return fmt.Errorf("failed to send interrupt signal: %w\n", err)
-				}
-				return fmt.Errorf("command did not exit within the specified duration, killing")
-			}
-		}
+	log.Info().Msgf("Waiting up to %s for process %d to exit...", stopGracePeriod, cmd.Process.Pid)
+	select {
+	case <-done:
+		log.Info().Msgf("Process %d exited", cmd.Process.Pid)
+		return nil
+	case <-time.After(stopGracePeriod):
+		log.Warn().Msgf("Process %d did not exit within %s, killing it", cmd.Process.Pid, stopGracePeriod)
+		return killProcess(cmd, done)
+	}
+}
+
+func killProcess(cmd *exec.Cmd, done <-chan error) error {
+	if err := cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("failed to kill process %d: %w", cmd.Process.Pid, err)
 	}
+	<-done
+	return ErrProcessKilled
 }
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunInteractiveStopGracefully(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := RunInteractive(ctx, true, "sh", t.TempDir(), "-c", "exec sleep 10")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, ErrProcessKilled)
+}
+
+func TestRunInteractiveKillAfterGracePeriod(t *testing.T) {
+	SetStopGracePeriod(200 * time.Millisecond)
+	t.Cleanup(func() { SetStopGracePeriod(DefaultStopGracePeriod) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The process ignores the interrupt, so it can only be killed
+	start := time.Now()
+	_, err := RunInteractive(ctx, true, "sh", t.TempDir(), "-c", "trap '' INT; exec sleep 10")
+	assert.ErrorIs(t, err, ErrProcessKilled)
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRunInteractiveCommandTimeout(t *testing.T) {
+	ctx := WithCommandTimeout(context.Background(), 100*time.Millisecond)
+
+	_, err := RunInteractive(ctx, true, "sh", t.TempDir(), "-c", "exec sleep 10")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "command timed out after 100ms")
+}
@@ -17,3 +17,13 @@ func CmdSetForeground(cmd *exec.Cmd) {
 		}
 	}
 }
+
+// setProcessGroup starts the process in its own process group, so signals sent to the process group of mach-composer
+// by the terminal are not delivered to it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd())
+}
@@ -2,7 +2,19 @@
 
 package utils
 
-import "os/exec"
+import (
+	"os"
+	"os/exec"
+
+	"github.com/mattn/go-isatty"
+)
 
 func CmdSetForeground(cmd *exec.Cmd) {
 }
+
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
@@ -2,45 +2,94 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"github.com/rs/zerolog/log"
-	"github.com/zclconf/go-cty/cty"
-	"github.com/zclconf/go-cty/cty/json"
+	"github.com/hashicorp/go-version"
 	"os"
 	"os/exec"
 )
 
-// RunTerraform will execute a terraform command with the given arguments in the given directory.
-func RunTerraform(ctx context.Context, cwd string, catchOutputs bool, args ...string) (string, error) {
-	if _, err := os.Stat(cwd); err != nil {
-		if os.IsNotExist(err) {
-			return "", fmt.Errorf("the generated files are not found: %w", err)
-		}
+// TerraformBinaryEnv is the environment variable that overrides the configured terraform executable
+const TerraformBinaryEnv = "MC_TERRAFORM_BINARY"
+
+const defaultTerraformBinary = "terraform"
+
+var terraformBinary = defaultTerraformBinary
+
+// SetTerraformBinary sets the name or path of the executable used to run terraform commands, which can also be a
+// compatible one like OpenTofu. An empty value resets it to terraform.
+func SetTerraformBinary(binary string) {
+	if binary == "" {
+		binary = defaultTerraformBinary
 	}
+	terraformBinary = binary
+}
 
-	execPath, err := exec.LookPath("terraform")
+// TerraformBinary returns the name or path of the executable used to run terraform commands
+func TerraformBinary() string {
+	return terraformBinary
+}
+
+// TerraformVersion returns the version reported by the terraform executable
+func TerraformVersion(ctx context.Context) (*version.Version, error) {
+	execPath, err := exec.LookPath(terraformBinary)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return RunInteractive(ctx, catchOutputs, execPath, cwd, args...)
+	output, err := RunInteractive(ctx, true, execPath, "", "version", "-json")
+	if err != nil {
+		return nil, err
+	}
+	return parseTerraformVersion(output)
 }
 
-func GetTerraformOutputs(ctx context.Context, path string) (cty.Value, error) {
-	var data json.SimpleJSONValue
+// parseTerraformVersion parses the output of the version -json command
+func parseTerraformVersion(output string) (*version.Version, error) {
+	// OpenTofu reports its version using the same field
+	var data struct {
+		Version string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal([]byte(output), &data); err != nil {
+		return nil, fmt.Errorf("failed to parse the version of %s: %w", terraformBinary, err)
+	}
 
-	output, err := RunTerraform(ctx, path, true, "output", "-json")
+	return version.NewVersion(data.Version)
+}
+
+// CheckTerraformVersion verifies that the version of the terraform executable satisfies the given constraint
+func CheckTerraformVersion(ctx context.Context, constraint string) error {
+	constraints, err := version.NewConstraint(constraint)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to get terraform output: %s", err.Error())
-		return cty.NilVal, err
+		return fmt.Errorf("invalid terraform version constraint %s: %w", constraint, err)
 	}
 
-	log.Debug().Str("output", output).Msgf("Fetched terraform output")
+	v, err := TerraformVersion(ctx)
+	if err != nil {
+		return err
+	}
+	return checkTerraformVersion(v, constraints)
+}
+
+func checkTerraformVersion(v *version.Version, constraints version.Constraints) error {
+	if !constraints.Check(v) {
+		return fmt.Errorf("%s version %s does not satisfy the required version %s", terraformBinary, v, constraints)
+	}
+	return nil
+}
+
+// RunTerraform will execute a terraform command with the given arguments in the given directory.
+func RunTerraform(ctx context.Context, cwd string, catchOutputs bool, args ...string) (string, error) {
+	if _, err := os.Stat(cwd); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("the generated files are not found: %w", err)
+		}
+	}
 
-	if err = data.UnmarshalJSON([]byte(output)); err != nil {
-		log.Error().Err(err).Str("output", output).Msgf("failed to unmarshal terraform output: %s", err.Error())
-		return cty.NilVal, err
+	execPath, err := exec.LookPath(terraformBinary)
+	if err != nil {
+		return "", err
 	}
 
-	return data.Value, nil
+	return RunInteractive(ctx, catchOutputs, execPath, cwd, args...)
 }
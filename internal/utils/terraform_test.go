@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTerraformVersion(t *testing.T) {
+	// OpenTofu reports its version like terraform does
+	v, err := parseTerraformVersion(`{"terraform_version":"1.6.2","platform":"linux_amd64"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "1.6.2", v.String())
+
+	_, err = parseTerraformVersion("Terraform v1.6.2")
+	assert.Error(t, err)
+
+	assert.NoError(t, checkTerraformVersion(v, version.MustConstraints(version.NewConstraint(">= 1.5.0, < 2.0.0"))))
+	assert.ErrorContains(t, checkTerraformVersion(v, version.MustConstraints(version.NewConstraint("~> 1.7.0"))),
+		"does not satisfy the required version")
+
+	// The constraint is verified before running terraform
+	assert.Error(t, CheckTerraformVersion(context.Background(), "not a constraint"))
+}
+
+func TestSetTerraformBinaryDefault(t *testing.T) {
+	SetTerraformBinary("tofu")
+	assert.Equal(t, "tofu", terraformBinary)
+
+	SetTerraformBinary("")
+	assert.Equal(t, "terraform", terraformBinary)
+}
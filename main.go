@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"github.com/mach-composer/mach-composer-cli/internal/cli"
 	"github.com/mach-composer/mach-composer-cli/internal/cmd"
 	"os"
@@ -9,9 +10,15 @@ import (
 //go:generate go run tools/cli-docs/main.go docs/src/reference/cli/
 func main() {
 	if err := cmd.RootCmd.Execute(); err != nil {
+		// HandleErr exits with the code of an ExitCodeError itself
 		if cmd.RootCmd.SilenceErrors {
 			cli.HandleErr(err)
 		}
+
+		var exitErr *cli.ExitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }